@@ -2,16 +2,23 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -19,7 +26,9 @@ import (
 	"github.com/johnayoung/llm-consensus/internal/consensus"
 	"github.com/johnayoung/llm-consensus/internal/output"
 	"github.com/johnayoung/llm-consensus/internal/provider"
+	"github.com/johnayoung/llm-consensus/internal/repotools"
 	"github.com/johnayoung/llm-consensus/internal/runner"
+	"github.com/johnayoung/llm-consensus/internal/schema"
 	"github.com/johnayoung/llm-consensus/internal/ui"
 )
 
@@ -30,10 +39,7 @@ var (
 	date    = "unknown"
 )
 
-const (
-	defaultJudge   = "gpt-5.2-pro-2025-12-11"
-	defaultTimeout = 120 * time.Second
-)
+const defaultJudge = "gpt-5.2-pro-2025-12-11"
 
 // ProviderType identifies which LLM provider to use.
 type ProviderType int
@@ -42,6 +48,8 @@ const (
 	ProviderOpenAI ProviderType = iota
 	ProviderAnthropic
 	ProviderGoogle
+	ProviderMistral
+	ProviderCohere
 )
 
 // Known models mapped to their providers.
@@ -58,19 +66,63 @@ var knownModels = map[string]ProviderType{
 
 	// Google
 	"gemini-3-pro-preview": ProviderGoogle,
+
+	// Mistral
+	"mistral-large-latest":    ProviderMistral,
+	"mistral-medium-latest":   ProviderMistral,
+	"mistral-small-latest":    ProviderMistral,
+	"magistral-medium-latest": ProviderMistral,
+	"codestral-latest":        ProviderMistral,
+
+	// Cohere
+	"command-a-03-2025":   ProviderCohere,
+	"command-r-plus":      ProviderCohere,
+	"command-r":           ProviderCohere,
+	"command-r7b-12-2024": ProviderCohere,
 }
 
+// Consensus strategies.
+const (
+	strategyJudge = "judge"
+	strategyVote  = "vote"
+)
+
 type config struct {
-	models  []string
-	judge   string
-	file    string
-	output  string
-	dataDir string
-	timeout time.Duration
-	prompt  string
-	quiet   bool
-	json    bool
-	noSave  bool
+	models      []string // model names or fallback chains "a|b"
+	judges      []string // tried in order until one answers
+	file        string
+	output      string
+	dataDir     string
+	timeouts    provider.Timeouts
+	httpClient  *http.Client // shared by providers, enforcing timeouts.Connect
+	retries     int
+	incomplete  runner.IncompletePolicy
+	prompt      string
+	quiet       bool
+	json        bool
+	noSave      bool
+	continues   string
+	history     []provider.Message
+	attachments []provider.Attachment
+	schema      *schema.Schema
+	strategy    string
+	tieBreak    bool
+	endpoints   []provider.Endpoint
+	limiter     *provider.Limiter
+	breaker     *provider.Breaker
+	logFile     string
+	routing     provider.OpenRouterRouting
+
+	// repo is the codebase the panel may explore with read-only tools
+	repo         *repotools.Repo
+	maxToolSteps int
+
+	// Generation parameters: defaults for the panel, per-model overrides
+	// merged over them, and the judge's own settings
+	params         provider.Params
+	modelParams    map[string]provider.Params
+	judgeParams    provider.Params
+	judgeReasoning bool
 }
 
 func main() {
@@ -85,10 +137,16 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	if cfg.repo != nil {
+		defer cfg.repo.Close()
+	}
 
-	// Setup context with signal handling for graceful shutdown
+	// Setup context with signal handling for graceful shutdown, ending the
+	// whole run, judge included, at --run-timeout
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
+	ctx, cancelRun := provider.WithTimeout(ctx, provider.TimeoutRun, cfg.timeouts.Run)
+	defer cancelRun()
 
 	// Determine if we should show UI (interactive terminal and not quiet)
 	// Show UI even when --output is specified (progress goes to stderr, JSON to file)
@@ -96,10 +154,18 @@ func run() error {
 	startTime := time.Now()
 
 	// Initialize providers based on requested models
-	registry, err := initRegistry(cfg.models, cfg.judge)
+	registry, err := initRegistry(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	if cfg.logFile != "" {
+		f, err := os.OpenFile(cfg.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("opening log file: %w", err)
+		}
+		defer f.Close()
+		registry.Use(provider.Logging(slog.New(slog.NewJSONHandler(f, nil))))
+	}
 
 	if showUI {
 		ui.PrintHeader(os.Stderr, cfg.prompt)
@@ -110,9 +176,35 @@ func run() error {
 	// Setup progress display
 	progress := ui.NewProgress(os.Stderr, cfg.models, !showUI)
 	progress.Start()
+	entries := make(map[string]string) // model -> the panel entry it's in
+	for _, entry := range cfg.models {
+		for _, model := range runner.SplitChain(entry) {
+			entries[model] = entry
+		}
+	}
+	cfg.limiter.OnWait(func(model string, wait time.Duration) {
+		progress.ModelWaiting(entries[model], wait)
+	})
 
 	// Create runner with timeout and callbacks
-	r := runner.New(registry, cfg.timeout)
+	r := runner.New(registry, cfg.timeouts.PerModel)
+	policy := runner.DefaultRetryPolicy
+	policy.MaxAttempts = cfg.retries + 1
+	r.WithRetry(policy)
+	r.WithIncomplete(cfg.incomplete)
+	r.WithBreaker(cfg.breaker, func(model string) string {
+		return providerName(model, cfg)
+	})
+	r.WithParams(cfg.params, cfg.modelParams)
+	r.WithHistory(cfg.history)
+	r.WithAttachments(cfg.attachments)
+	r.WithSchema(cfg.schema)
+	if cfg.repo != nil {
+		for _, t := range cfg.repo.Tools() {
+			r.WithTool(t.Tool, t.Handler)
+		}
+		r.WithMaxSteps(cfg.maxToolSteps)
+	}
 	r.WithCallbacks(&runner.Callbacks{
 		OnModelStart: func(model string) {
 			progress.ModelStarted(model)
@@ -126,6 +218,12 @@ func run() error {
 		OnModelError: func(model string, err error) {
 			progress.ModelFailed(model, err)
 		},
+		OnModelRetry: func(model string, attempt, maxAttempts int, delay time.Duration, err error) {
+			progress.ModelRetrying(model, attempt, maxAttempts, delay)
+		},
+		OnModelFallback: func(model, next string, err error) {
+			progress.ModelFallingBack(model, next)
+		},
 	})
 
 	// Execute queries in parallel with streaming
@@ -135,52 +233,115 @@ func run() error {
 	progress.Stop()
 
 	if err != nil {
-		return fmt.Errorf("running queries: %w", err)
+		return fmt.Errorf("running queries: %w", provider.TimeoutCause(ctx, err))
 	}
 
 	if showUI {
 		ui.PrintSuccess(os.Stderr, fmt.Sprintf("Received responses from %d models", len(result.Responses)))
 		fmt.Fprintln(os.Stderr)
-		ui.PrintPhase(os.Stderr, "Synthesizing consensus...")
+		if cfg.strategy == strategyVote {
+			ui.PrintPhase(os.Stderr, "Voting on fields...")
+		} else {
+			ui.PrintPhase(os.Stderr, "Synthesizing consensus...")
+		}
 		fmt.Fprintln(os.Stderr)
 	}
 
-	// Get consensus from judge with streaming
-	judgeProvider, err := registry.Get(cfg.judge)
-	if err != nil {
-		return fmt.Errorf("judge model %s: %w", cfg.judge, err)
+	var (
+		judgeResp provider.Response
+		vote      *consensus.Vote
+	)
+	if cfg.strategy == strategyVote {
+		vote, err = consensus.VoteFields(result.Responses)
+		if err != nil {
+			return fmt.Errorf("voting: %w", err)
+		}
+		for _, model := range vote.Skipped {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: answer is not a JSON object, left out of the vote", model))
+		}
 	}
 
-	judge := consensus.NewJudge(judgeProvider, cfg.judge)
-
-	// Setup judge progress
-	judgeProgress := ui.NewProgress(os.Stderr, []string{cfg.judge}, !showUI)
-	judgeProgress.Start()
-	judgeProgress.ModelStarted(cfg.judge)
+	// The judge synthesizes the consensus, or when voting only settles
+	// disputed fields, if asked to. Judges are tried in order until one
+	// answers.
+	var judgeModel string // the judge that answered; empty if none ran
+	if vote == nil || (cfg.tieBreak && len(vote.Disputed()) > 0) {
+		for i, model := range cfg.judges {
+			judgeModel = model
+			judgeResp, err = synthesize(ctx, cfg, registry, model, result, vote, showUI)
+			if err == nil {
+				break
+			}
+			if i == len(cfg.judges)-1 || ctx.Err() != nil || provider.IsUserError(err) {
+				return fmt.Errorf("consensus synthesis: %w", provider.TimeoutCause(ctx, err))
+			}
+			result.Warnings = append(result.Warnings, fmt.Sprintf("judge %s: %v; falling back to %s", model, err, cfg.judges[i+1]))
+		}
+	}
 
-	consensusResp, err := judge.SynthesizeStream(ctx, cfg.prompt, result.Responses, func(chunk string) {
-		judgeProgress.ModelStreaming(cfg.judge, chunk)
-	})
+	if showUI {
+		ui.PrintSuccess(os.Stderr, "Consensus reached!")
+	}
 
-	judgeProgress.ModelCompleted(cfg.judge)
-	judgeProgress.Stop()
+	consensusResp := judgeResp.Content
+	if judgeResp.Truncated() {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("judge %s: consensus truncated (finish reason %s)", judgeModel, judgeResp.FinishReason))
+	}
 
-	if err != nil {
-		return fmt.Errorf("consensus synthesis: %w", err)
+	usage := judgeResp.Usage
+	for _, resp := range append(result.Responses, result.Incomplete...) {
+		usage = usage.Add(resp.Usage)
 	}
 
-	if showUI {
-		ui.PrintSuccess(os.Stderr, "Consensus reached!")
+	// With a schema or voting the consensus is emitted as a JSON value,
+	// not a string
+	var (
+		consensusOut any = consensusResp
+		fields       []consensus.Field
+	)
+	switch {
+	case vote != nil:
+		for _, f := range vote.Disputed() {
+			if f.ResolvedBy == "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("field %s disputed (%.0f%% agreement)", f.Path, f.Agreement*100))
+			}
+		}
+		data, err := json.MarshalIndent(vote.Value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding vote: %w", err)
+		}
+		consensusResp = string(data)
+		consensusOut = vote.Value
+		fields = vote.Fields
+	case cfg.schema != nil:
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(consensusResp), "", "  ") == nil {
+			consensusResp = buf.String()
+		}
+		consensusOut = json.RawMessage(consensusResp)
 	}
 
 	// Format output
 	out := output.Result{
 		Prompt:       cfg.prompt,
+		History:      cfg.history,
+		Continues:    cfg.continues,
+		Attachments:  attachmentNames(cfg.attachments),
 		Responses:    result.Responses,
-		Consensus:    consensusResp,
-		Judge:        cfg.judge,
+		Consensus:    consensusOut,
+		Strategy:     cfg.strategy,
+		Fields:       fields,
+		Judge:        judgeModel,
+		JudgeChain:   judgeChain(judgeModel, cfg.judges),
+		JudgeUsage:   judgeResp.Usage,
+		JudgeFinish:  judgeResp.FinishReason,
+		Usage:        usage,
 		Warnings:     result.Warnings,
+		Failures:     result.Failures,
 		FailedModels: result.FailedModels,
+		Skipped:      result.SkippedModels,
+		Chains:       result.Chains,
+		Incomplete:   result.Incomplete,
 	}
 
 	// Determine output path
@@ -206,8 +367,12 @@ func run() error {
 			}
 		}
 
-		// Save consensus as markdown for easy reading
+		// Save consensus as markdown for easy reading, or as JSON in
+		// schema and vote modes
 		consensusPath := filepath.Join(runDir, "consensus.md")
+		if cfg.schema != nil || vote != nil {
+			consensusPath = filepath.Join(runDir, "consensus.json")
+		}
 		if err := os.WriteFile(consensusPath, []byte(consensusResp), 0644); err != nil {
 			if showUI {
 				ui.PrintError(os.Stderr, fmt.Sprintf("Failed to save consensus: %v", err))
@@ -215,6 +380,15 @@ func run() error {
 		}
 	}
 
+	// Save each model's tool calls and results beside the output
+	if outputPath != "" {
+		if err := saveTranscripts(filepath.Join(filepath.Dir(outputPath), "transcripts"), result.Responses); err != nil {
+			if showUI {
+				ui.PrintError(os.Stderr, fmt.Sprintf("Failed to save tool transcripts: %v", err))
+			}
+		}
+	}
+
 	// Write output
 	if outputPath != "" {
 		// Write JSON to file
@@ -245,7 +419,11 @@ func run() error {
 
 		// Print individual model responses
 		for _, resp := range result.Responses {
-			ui.PrintModelResponse(os.Stderr, resp.Model, resp.Provider, resp.Content, resp.Latency)
+			providerName := resp.Provider
+			if resp.Upstream != "" {
+				providerName += " via " + resp.Upstream
+			}
+			ui.PrintModelResponse(os.Stderr, resp.Model, providerName, resp.Content, resp.Latency)
 		}
 
 		// Print consensus
@@ -257,10 +435,16 @@ func run() error {
 			len(result.Responses),
 			len(result.FailedModels),
 			time.Since(startTime))
+		if usage != (provider.Usage{}) {
+			ui.PrintTokenUsage(os.Stderr, usage.InputTokens, usage.OutputTokens, usage.ReasoningTokens, usage.CachedTokens)
+		}
 
-		// Print warnings if any
-		if len(result.Warnings) > 0 {
+		// Print failures and warnings if any
+		if len(result.Failures) > 0 || len(result.Warnings) > 0 {
 			fmt.Fprintln(os.Stderr)
+			for _, f := range result.Failures {
+				ui.PrintError(os.Stderr, fmt.Sprintf("%s: %s", f.Model, f.Error))
+			}
 			for _, w := range result.Warnings {
 				ui.PrintError(os.Stderr, w)
 			}
@@ -275,6 +459,53 @@ func run() error {
 	return nil
 }
 
+// synthesize asks one judge model for the consensus over the panel's
+// responses, or with a vote, to settle its disputed fields.
+func synthesize(ctx context.Context, cfg *config, registry *provider.Registry, model string, result *runner.Result, vote *consensus.Vote, showUI bool) (provider.Response, error) {
+	ctx, cancel := provider.WithTimeout(ctx, provider.TimeoutPerModel, cfg.timeouts.PerModel)
+	defer cancel()
+
+	judgeProvider, err := registry.Get(model)
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("judge model %s: %w", model, err)
+	}
+	judgeProvider = provider.Chain(cfg.breaker.Middleware(providerName(model, cfg), model))(judgeProvider)
+
+	judge := consensus.NewJudge(judgeProvider, model).
+		WithParams(cfg.judgeParams).
+		WithHistory(cfg.history).
+		WithReasoning(cfg.judgeReasoning).
+		WithSchema(cfg.schema)
+
+	// Setup judge progress
+	judgeProgress := ui.NewProgress(os.Stderr, []string{model}, !showUI)
+	judgeProgress.Start()
+	defer judgeProgress.Stop()
+	cfg.limiter.OnWait(judgeProgress.ModelWaiting)
+	judgeProgress.ModelStarted(model)
+
+	onChunk := func(chunk string) {
+		judgeProgress.ModelStreaming(model, chunk)
+	}
+	var resp provider.Response
+	if vote != nil {
+		resp, err = judge.TieBreak(ctx, cfg.prompt, vote, onChunk)
+	} else {
+		resp, err = judge.SynthesizeStream(ctx, cfg.prompt, result.Responses, onChunk)
+	}
+	judgeProgress.ModelCompleted(model)
+	return resp, provider.TimeoutCause(ctx, err)
+}
+
+// judgeChain returns the judges for the output when one ran and there
+// are fallbacks.
+func judgeChain(judgeModel string, judges []string) []string {
+	if judgeModel == "" || len(judges) < 2 {
+		return nil
+	}
+	return judges
+}
+
 // generateRunID creates a unique run identifier using timestamp + random suffix.
 // Format: 20260112-143052-a1b2c3
 func generateRunID() string {
@@ -297,24 +528,120 @@ func getVersion() string {
 
 func parseFlags() (*config, error) {
 	var (
-		modelsStr   string
-		judge       string
-		file        string
-		outputPath  string
-		dataDir     string
-		timeout     int
-		quiet       bool
-		jsonOutput  bool
-		noSave      bool
-		showVersion bool
+		modelsStr         string
+		judge             string
+		file              string
+		outputPath        string
+		dataDir           string
+		timeout           int
+		connectTimeout    int
+		firstTokenTimeout int
+		idleTimeout       int
+		runTimeout        int
+		retries           int
+		incomplete        string
+		endpoints         string
+		continueRun       string
+		schemaFile        string
+		strategy          string
+		tieBreak          bool
+		repoDir           string
+		maxToolSteps      int
+		orOrder           string
+		orNoFallbacks     bool
+		orDataCollection  string
+		rateLimits        = provider.NewLimiter()
+		logFile           string
+		breakerFailures   int
+		breakerCooldown   int
+		quiet             bool
+		jsonOutput        bool
+		noSave            bool
+		showVersion       bool
+		params            provider.Params
+		judgeParams       provider.Params
+		modelParams       []string
+		judgeReasoning    bool
+		attachments       []provider.Attachment
 	)
 
-	flag.StringVar(&modelsStr, "models", "", "Comma-separated list of models to query (required)")
-	flag.StringVar(&judge, "judge", defaultJudge, "Model to use for consensus synthesis")
+	flag.StringVar(&modelsStr, "models", "", "Comma-separated list of models to query, each optionally a fallback chain a|b (required)")
+	flag.StringVar(&judge, "judge", defaultJudge, "Model to use for consensus synthesis, or a comma-separated list tried in order")
 	flag.StringVar(&file, "file", "", "Read prompt from file")
 	flag.StringVar(&outputPath, "output", "", "Write JSON output to specific file (overrides auto-save)")
 	flag.StringVar(&dataDir, "data-dir", "data", "Directory for auto-saved runs")
-	flag.IntVar(&timeout, "timeout", 120, "Per-model timeout in seconds")
+	flag.IntVar(&timeout, "timeout", seconds(provider.DefaultTimeouts.PerModel), "Per-model timeout in seconds, across retries and tool calls; also applies to the judge")
+	flag.IntVar(&connectTimeout, "connect-timeout", seconds(provider.DefaultTimeouts.Connect), "Seconds to connect to a provider")
+	flag.IntVar(&firstTokenTimeout, "first-token-timeout", seconds(provider.DefaultTimeouts.FirstToken), "Seconds a model may take to stream its first token; 0 disables")
+	flag.IntVar(&idleTimeout, "idle-timeout", seconds(provider.DefaultTimeouts.Idle), "Seconds a stream may go silent between tokens; 0 disables")
+	flag.IntVar(&runTimeout, "run-timeout", seconds(provider.DefaultTimeouts.Run), "Seconds the whole run, judge included, may take; 0 disables")
+	flag.IntVar(&retries, "retries", runner.DefaultRetryPolicy.MaxAttempts-1, "Retries per model after rate limits, overloads and server errors")
+	flag.StringVar(&incomplete, "incomplete", string(runner.IncompleteInclude), "Answers cut off by a timeout: include (marked for the judge), exclude (saved, left out of the consensus) or fail")
+	flag.StringVar(&continueRun, "continue", "", "Run ID in the data directory to continue as a follow-up conversation")
+	flag.StringVar(&schemaFile, "schema", "", "JSON Schema file; panel answers and the consensus must be JSON conforming to it")
+	flag.StringVar(&strategy, "strategy", strategyJudge, "Consensus strategy: judge (LLM synthesis) or vote (field-level vote over JSON answers)")
+	flag.BoolVar(&tieBreak, "tie-break", false, "With --strategy vote, ask the judge to settle disputed fields")
+	flag.StringVar(&repoDir, "repo", "", "Let panel models list, read and grep files under this directory")
+	flag.IntVar(&maxToolSteps, "max-tool-steps", runner.DefaultMaxSteps, "Rounds of tool calls per model with --repo before it must answer")
+	flag.StringVar(&endpoints, "endpoints", "", "JSON file declaring OpenAI-compatible endpoints")
+	flag.StringVar(&orOrder, "openrouter-order", "", "Comma-separated upstream providers OpenRouter should try first")
+	flag.BoolVar(&orNoFallbacks, "openrouter-no-fallbacks", false, "Don't let OpenRouter fall back to other upstream providers")
+	flag.StringVar(&orDataCollection, "openrouter-data-collection", "", "OpenRouter data collection policy: allow or deny")
+	flag.Func("system", "System prompt sent to every panel model", func(v string) error {
+		return setParam(&params, "system", v)
+	})
+	flag.Func("temperature", "Sampling temperature for panel models", func(v string) error {
+		return setParam(&params, "temperature", v)
+	})
+	flag.Func("max-tokens", "Maximum output tokens for panel models", func(v string) error {
+		return setParam(&params, "max_tokens", v)
+	})
+	flag.Func("top-p", "Nucleus sampling top_p for panel models", func(v string) error {
+		return setParam(&params, "top_p", v)
+	})
+	flag.Func("stop", "Stop sequence for panel models (repeatable)", func(v string) error {
+		return setParam(&params, "stop", v)
+	})
+	flag.Func("seed", "Sampling seed for panel models that support one", func(v string) error {
+		return setParam(&params, "seed", v)
+	})
+	flag.Func("reasoning-effort", "Reasoning effort for panel models: none, minimal, low, medium or high", func(v string) error {
+		return setParam(&params, "reasoning_effort", v)
+	})
+	flag.Func("thinking-budget", "Thinking token budget for panel models (Anthropic, Gemini, Cohere)", func(v string) error {
+		return setParam(&params, "thinking_budget", v)
+	})
+	flag.Func("model-param", "Per-model override as model:key=value, e.g. claude-opus-4-5:max_tokens=32000 (repeatable)", func(v string) error {
+		modelParams = append(modelParams, v)
+		return nil
+	})
+	flag.Func("judge-param", "Judge setting as key=value, e.g. temperature=0 (repeatable)", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("want key=value, got %q", v)
+		}
+		return setParam(&judgeParams, key, value)
+	})
+	flag.StringVar(&logFile, "log", "", "Append a JSON log line for every provider request to this file")
+	flag.IntVar(&breakerFailures, "breaker-failures", 3, "Consecutive outage failures (overloads, server and network errors, timeouts) before a model or provider is benched; 0 disables")
+	flag.IntVar(&breakerCooldown, "breaker-cooldown", 300, "Seconds a benched model or provider is skipped before it is probed again")
+	flag.Func("rate-limit", "Client-side limit as name:rpm=N,tpm=N for a provider (e.g. anthropic) or model (repeatable)", func(v string) error {
+		key, limit, err := parseRateLimit(v)
+		if err != nil {
+			return err
+		}
+		rateLimits.SetLimit(key, limit)
+		return nil
+	})
+	flag.Func("attach", "Image, PDF or text file sent to every panel model (repeatable)", func(v string) error {
+		a, err := loadAttachment(v)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, a)
+		return nil
+	})
+	flag.BoolVar(&judgeReasoning, "judge-reasoning", false, "Show the judge each model's reasoning, not just its final answer")
 	flag.BoolVar(&quiet, "quiet", false, "Suppress progress output")
 	flag.BoolVar(&quiet, "q", false, "Suppress progress output (shorthand)")
 	flag.BoolVar(&jsonOutput, "json", false, "Output JSON to stdout (no interactive display, no auto-save)")
@@ -338,16 +665,137 @@ func parseFlags() (*config, error) {
 		models[i] = strings.TrimSpace(models[i])
 	}
 
+	var judges []string
+	for _, j := range strings.Split(judge, ",") {
+		judges = append(judges, strings.TrimSpace(j))
+	}
+
+	timeouts := provider.Timeouts{
+		Connect:    time.Duration(connectTimeout) * time.Second,
+		FirstToken: time.Duration(firstTokenTimeout) * time.Second,
+		Idle:       time.Duration(idleTimeout) * time.Second,
+		PerModel:   time.Duration(timeout) * time.Second,
+		Run:        time.Duration(runTimeout) * time.Second,
+	}
+
 	cfg := &config{
 		models:  models,
-		judge:   judge,
+		judges:  judges,
 		file:    file,
 		output:  outputPath,
 		dataDir: dataDir,
-		timeout: time.Duration(timeout) * time.Second,
+		retries: retries,
 		quiet:   quiet,
 		json:    jsonOutput,
 		noSave:  noSave,
+
+		params:         params,
+		judgeParams:    judgeParams,
+		limiter:        rateLimits,
+		logFile:        logFile,
+		judgeReasoning: judgeReasoning,
+		attachments:    attachments,
+		strategy:       strategy,
+		tieBreak:       tieBreak,
+		maxToolSteps:   maxToolSteps,
+		incomplete:     runner.IncompletePolicy(incomplete),
+		timeouts:       timeouts,
+		httpClient:     timeouts.HTTPClient(),
+	}
+
+	switch cfg.incomplete {
+	case runner.IncompleteInclude, runner.IncompleteExclude, runner.IncompleteFail:
+	default:
+		return nil, fmt.Errorf("--incomplete must be %s, %s or %s", runner.IncompleteInclude, runner.IncompleteExclude, runner.IncompleteFail)
+	}
+
+	switch strategy {
+	case strategyJudge, strategyVote:
+	default:
+		return nil, fmt.Errorf("--strategy must be %s or %s", strategyJudge, strategyVote)
+	}
+
+	// The budget counts toward max_tokens, so it can't use all of it
+	if params.ThinkingBudget > 0 && params.MaxTokens > 0 && params.ThinkingBudget >= params.MaxTokens {
+		return nil, fmt.Errorf("--thinking-budget must be below --max-tokens")
+	}
+	if judgeParams.ThinkingBudget > 0 && judgeParams.MaxTokens > 0 && judgeParams.ThinkingBudget >= judgeParams.MaxTokens {
+		return nil, fmt.Errorf("--judge-param thinking_budget must be below max_tokens")
+	}
+
+	if len(modelParams) > 0 {
+		overrides, err := parseModelParams(modelParams, cfg.chainModels(), judges)
+		if err != nil {
+			return nil, err
+		}
+		cfg.modelParams = overrides
+	}
+
+	if orOrder != "" {
+		for _, name := range strings.Split(orOrder, ",") {
+			cfg.routing.Order = append(cfg.routing.Order, strings.TrimSpace(name))
+		}
+	}
+	if orNoFallbacks {
+		allow := false
+		cfg.routing.AllowFallbacks = &allow
+	}
+	switch orDataCollection {
+	case "", "allow", "deny":
+		cfg.routing.DataCollection = orDataCollection
+	default:
+		return nil, fmt.Errorf("--openrouter-data-collection must be allow or deny")
+	}
+
+	if continueRun != "" {
+		history, err := loadHistory(dataDir, continueRun)
+		if err != nil {
+			return nil, err
+		}
+		cfg.continues = continueRun
+		cfg.history = history
+	}
+
+	if schemaFile != "" {
+		data, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading schema: %w", err)
+		}
+		s, err := schema.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		cfg.schema = s
+	}
+
+	if breakerFailures > 0 {
+		breaker, err := provider.NewBreaker(filepath.Join(dataDir, "circuits.json"), breakerFailures, time.Duration(breakerCooldown)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("loading circuit breaker state: %w", err)
+		}
+		cfg.breaker = breaker
+	}
+
+	if retries < 0 {
+		return nil, fmt.Errorf("--retries must not be negative")
+	}
+	if maxToolSteps < 1 {
+		return nil, fmt.Errorf("--max-tool-steps must be at least 1")
+	}
+	if repoDir != "" {
+		repo, err := repotools.Open(repoDir)
+		if err != nil {
+			return nil, err
+		}
+		cfg.repo = repo
+	}
+
+	if endpoints != "" {
+		eps, err := loadEndpoints(endpoints)
+		if err != nil {
+			return nil, err
+		}
+		cfg.endpoints = eps
 	}
 
 	// Get prompt from: positional arg > file > stdin
@@ -360,6 +808,86 @@ func parseFlags() (*config, error) {
 	return cfg, nil
 }
 
+// parseModelParams parses "model:key=value" overrides. The model part is
+// everything before the last colon ahead of the "=", so prefixed names such
+// as ollama:llama3:8b work. Each model must be a panel model; the judge
+// takes its settings from --judge-param instead.
+func parseModelParams(specs []string, models, judges []string) (map[string]provider.Params, error) {
+	overrides := make(map[string]provider.Params)
+	for _, spec := range specs {
+		lhs, value, ok := strings.Cut(spec, "=")
+		i := strings.LastIndex(lhs, ":")
+		if !ok || i <= 0 {
+			return nil, fmt.Errorf("--model-param: want model:key=value, got %q", spec)
+		}
+		model, key := lhs[:i], lhs[i+1:]
+		if !slices.Contains(models, model) {
+			if slices.Contains(judges, model) {
+				return nil, fmt.Errorf("--model-param: %q is only the judge; use --judge-param to configure it", model)
+			}
+			return nil, fmt.Errorf("--model-param: model %q is not in --models", model)
+		}
+
+		p := overrides[model]
+		if err := setParam(&p, key, value); err != nil {
+			return nil, fmt.Errorf("--model-param %s: %w", spec, err)
+		}
+		overrides[model] = p
+	}
+	return overrides, nil
+}
+
+// setParam sets one generation parameter by its key. Stop sequences
+// accumulate so the key can be given more than once.
+func setParam(p *provider.Params, key, value string) error {
+	switch key {
+	case "system":
+		p.System = value
+	case "temperature":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("temperature must be a non-negative number, got %q", value)
+		}
+		p.Temperature = &f
+	case "max_tokens":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("max_tokens must be a positive integer, got %q", value)
+		}
+		p.MaxTokens = n
+	case "top_p":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 || f > 1 {
+			return fmt.Errorf("top_p must be between 0 and 1, got %q", value)
+		}
+		p.TopP = &f
+	case "stop":
+		p.Stop = append(p.Stop, value)
+	case "seed":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("seed must be an integer, got %q", value)
+		}
+		p.Seed = &n
+	case "reasoning_effort":
+		switch value {
+		case "none", "minimal", "low", "medium", "high":
+			p.ReasoningEffort = value
+		default:
+			return fmt.Errorf("reasoning_effort must be none, minimal, low, medium or high, got %q", value)
+		}
+	case "thinking_budget":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("thinking_budget must be a positive integer, got %q", value)
+		}
+		p.ThinkingBudget = n
+	default:
+		return fmt.Errorf("unknown parameter %q (want system, temperature, max_tokens, top_p, stop, seed, reasoning_effort or thinking_budget)", key)
+	}
+	return nil
+}
+
 func getPrompt(args []string, file string) (string, error) {
 	// Priority 1: Positional argument
 	if len(args) > 0 {
@@ -392,29 +920,90 @@ func getPrompt(args []string, file string) (string, error) {
 	return "", fmt.Errorf("no prompt provided: use positional argument, --file, or pipe to stdin")
 }
 
-func initRegistry(models []string, judge string) (*provider.Registry, error) {
+// chainModels returns every panel model, including fallbacks.
+func (cfg *config) chainModels() []string {
+	var models []string
+	for _, entry := range cfg.models {
+		models = append(models, runner.SplitChain(entry)...)
+	}
+	return models
+}
+
+func initRegistry(ctx context.Context, cfg *config) (*provider.Registry, error) {
 	registry := provider.NewRegistry()
 
-	// Collect all unique models (including judge)
+	// Collect all unique models (including fallbacks and judges)
 	needed := make(map[string]bool)
-	for _, m := range models {
+	for _, m := range cfg.chainModels() {
 		needed[m] = true
 	}
-	needed[judge] = true
+	if cfg.strategy != strategyVote || cfg.tieBreak {
+		for _, m := range cfg.judges {
+			needed[m] = true
+		}
+	}
+
+	// Local Ollama models are discovered from the server, not knownModels
+	var ollamaModels []string
+	for model := range needed {
+		if strings.HasPrefix(model, provider.OllamaPrefix) {
+			ollamaModels = append(ollamaModels, model)
+			delete(needed, model)
+		}
+	}
+	if len(ollamaModels) > 0 {
+		if err := registerOllama(ctx, registry, ollamaModels, cfg); err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize providers for each model
 	for model := range needed {
-		p, err := createProvider(model)
+		p, err := createProvider(model, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("initializing provider for %s: %w", model, err)
 		}
-		registry.Register(model, p)
+		registry.Register(model, p, middlewares(cfg, providerName(model, cfg), model)...)
 	}
 
 	return registry, nil
 }
 
-func createProvider(model string) (provider.Provider, error) {
+func createProvider(model string, cfg *config) (provider.Provider, error) {
+	// Any OpenRouter slug is accepted; OpenRouter validates it
+	if strings.HasPrefix(model, provider.OpenRouterPrefix) {
+		opts := []provider.OpenRouterOption{provider.WithOpenRouterHTTPClient(cfg.httpClient)}
+		if len(cfg.routing.Order) > 0 || cfg.routing.AllowFallbacks != nil || cfg.routing.DataCollection != "" {
+			opts = append(opts, provider.WithOpenRouterRouting(cfg.routing))
+		}
+		return provider.NewOpenRouter(opts...)
+	}
+
+	// Azure deployments are named by the user, so any name is accepted
+	if strings.HasPrefix(model, provider.AzurePrefix) {
+		return provider.NewAzureOpenAI(provider.WithAzureHTTPClient(cfg.httpClient))
+	}
+
+	// Bedrock model IDs are validated by Bedrock itself
+	if strings.HasPrefix(model, provider.BedrockPrefix) {
+		return provider.NewBedrock(provider.WithBedrockHTTPClient(cfg.httpClient))
+	}
+
+	// Gemini on Vertex AI accepts any model the project has access to
+	if strings.HasPrefix(model, provider.VertexPrefix) {
+		return provider.NewGoogleVertex(provider.WithGoogleHTTPClient(cfg.httpClient))
+	}
+
+	// User-declared OpenAI-compatible endpoints: "<endpoint>:<model>" or a
+	// bare model listed by exactly one endpoint
+	if ep, ok := findEndpoint(model, cfg.endpoints); ok {
+		name := strings.TrimPrefix(model, ep.Name+":")
+		if len(ep.Models) > 0 && !slices.Contains(ep.Models, name) {
+			return nil, fmt.Errorf("unknown model %q; endpoint %s serves: %v", model, ep.Name, ep.Models)
+		}
+		return provider.NewChatCompletions(ep, provider.WithChatCompletionsHTTPClient(cfg.httpClient))
+	}
+
 	providerType, ok := knownModels[model]
 	if !ok {
 		// List available models for helpful error message
@@ -422,17 +1011,288 @@ func createProvider(model string) (provider.Provider, error) {
 		for m := range knownModels {
 			available = append(available, m)
 		}
+		available = append(available, provider.OpenRouterPrefix+"<vendor>/<model>", provider.AzurePrefix+"<deployment>", provider.BedrockPrefix+"<model-id>", provider.VertexPrefix+"<model>")
+		for _, ep := range cfg.endpoints {
+			available = append(available, ep.Name+":<model>")
+		}
 		return nil, fmt.Errorf("unknown model %q; available models: %v", model, available)
 	}
 
 	switch providerType {
 	case ProviderOpenAI:
-		return provider.NewOpenAI()
+		return provider.NewOpenAI(provider.WithOpenAIHTTPClient(cfg.httpClient))
 	case ProviderAnthropic:
-		return provider.NewAnthropic()
+		return provider.NewAnthropic(provider.WithAnthropicHTTPClient(cfg.httpClient))
 	case ProviderGoogle:
-		return provider.NewGoogle()
+		return provider.NewGoogle(provider.WithGoogleHTTPClient(cfg.httpClient))
+	case ProviderMistral:
+		return provider.NewMistral(provider.WithMistralHTTPClient(cfg.httpClient))
+	case ProviderCohere:
+		return provider.NewCohere(provider.WithCohereHTTPClient(cfg.httpClient))
 	default:
 		return nil, fmt.Errorf("unhandled provider type for model %s", model)
 	}
 }
+
+// middlewares returns the per-model provider middlewares: client-side rate
+// limits, then the first-token and idle timeouts. The circuit breaker is
+// applied by the runner and the judge instead, outside retries, so a run of
+// retries counts as one failure.
+func middlewares(cfg *config, providerName, model string) []provider.Middleware {
+	return []provider.Middleware{
+		cfg.limiter.Middleware(providerName, model),
+		cfg.timeouts.Middleware(),
+	}
+}
+
+// seconds returns d in whole seconds, for flag defaults.
+func seconds(d time.Duration) int {
+	return int(d / time.Second)
+}
+
+// providerName returns the name of the provider serving model, which
+// --rate-limit accepts in place of a model name.
+func providerName(model string, cfg *config) string {
+	switch {
+	case strings.HasPrefix(model, provider.OpenRouterPrefix):
+		return "openrouter"
+	case strings.HasPrefix(model, provider.AzurePrefix):
+		return "azure"
+	case strings.HasPrefix(model, provider.BedrockPrefix):
+		return "bedrock"
+	case strings.HasPrefix(model, provider.VertexPrefix):
+		return "vertex"
+	case strings.HasPrefix(model, provider.OllamaPrefix):
+		return "ollama"
+	}
+	if ep, ok := findEndpoint(model, cfg.endpoints); ok {
+		return ep.Name
+	}
+	switch knownModels[model] {
+	case ProviderAnthropic:
+		return "anthropic"
+	case ProviderGoogle:
+		return "google"
+	case ProviderMistral:
+		return "mistral"
+	case ProviderCohere:
+		return "cohere"
+	}
+	return "openai"
+}
+
+// parseRateLimit parses "name:rpm=N,tpm=N". The name is everything before
+// the last colon, so model names containing colons work.
+func parseRateLimit(spec string) (string, provider.RateLimit, error) {
+	var limit provider.RateLimit
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 {
+		return "", limit, fmt.Errorf("invalid rate limit %q: want name:rpm=N,tpm=N", spec)
+	}
+	for _, setting := range strings.Split(spec[i+1:], ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		n, err := strconv.Atoi(value)
+		if !ok || err != nil || n <= 0 {
+			return "", limit, fmt.Errorf("invalid rate limit %q: want name:rpm=N,tpm=N", spec)
+		}
+		switch key {
+		case "rpm":
+			limit.RequestsPerMinute = n
+		case "tpm":
+			limit.TokensPerMinute = n
+		default:
+			return "", limit, fmt.Errorf("invalid rate limit %q: unknown setting %q (want rpm or tpm)", spec, key)
+		}
+	}
+	return spec[:i], limit, nil
+}
+
+// findEndpoint resolves a model to the endpoint that serves it.
+func findEndpoint(model string, endpoints []provider.Endpoint) (provider.Endpoint, bool) {
+	if name, _, ok := strings.Cut(model, ":"); ok {
+		for _, ep := range endpoints {
+			if ep.Name == name {
+				return ep, true
+			}
+		}
+	}
+
+	var matches []provider.Endpoint
+	for _, ep := range endpoints {
+		for _, m := range ep.Models {
+			if m == model {
+				matches = append(matches, ep)
+			}
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], true
+	}
+	return provider.Endpoint{}, false
+}
+
+// loadHistory rebuilds the conversation of a saved run: its own history,
+// then its prompt and consensus as the latest user and assistant turns.
+func loadHistory(dataDir, runID string) ([]provider.Message, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, runID, "result.json"))
+	if err != nil {
+		return nil, fmt.Errorf("loading run %s: %w", runID, err)
+	}
+
+	var prev output.Result
+	if err := json.Unmarshal(data, &prev); err != nil {
+		return nil, fmt.Errorf("parsing run %s: %w", runID, err)
+	}
+
+	// Schema-mode runs store the consensus as a JSON value
+	answer, ok := prev.Consensus.(string)
+	if !ok {
+		data, err := json.Marshal(prev.Consensus)
+		if err != nil {
+			return nil, fmt.Errorf("parsing run %s: %w", runID, err)
+		}
+		answer = string(data)
+	}
+
+	history := append(prev.History,
+		provider.Message{Role: provider.RoleUser, Content: prev.Prompt},
+		provider.Message{Role: provider.RoleAssistant, Content: answer},
+	)
+	return history, nil
+}
+
+// maxAttachmentSize is the largest file --attach accepts. Providers cap
+// inline uploads at around 20 MB.
+const maxAttachmentSize = 20 << 20
+
+// loadAttachment reads a file for --attach. The MIME type comes from the
+// file extension, falling back to sniffing the content.
+func loadAttachment(path string) (provider.Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return provider.Attachment{}, fmt.Errorf("attachment: %w", err)
+	}
+	if info.Size() > maxAttachmentSize {
+		return provider.Attachment{}, fmt.Errorf("attachment %s: %d bytes exceeds the %d MiB limit", path, info.Size(), maxAttachmentSize>>20)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return provider.Attachment{}, fmt.Errorf("attachment: %w", err)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+
+	return provider.Attachment{
+		Name:     filepath.Base(path),
+		MIMEType: strings.TrimSpace(mimeType),
+		Data:     data,
+	}, nil
+}
+
+// saveTranscripts writes the tool exchange of each response that made tool
+// calls to dir/<model>.json.
+func saveTranscripts(dir string, responses []provider.Response) error {
+	for _, resp := range responses {
+		if len(resp.Transcript) == 0 {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(resp.Transcript, "", "  ")
+		if err != nil {
+			return err
+		}
+		name := fileNameUnsafe.ReplaceAllString(resp.Model, "_") + ".json"
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileNameUnsafe matches characters of model names, such as the slashes and
+// colons of prefixed names, that don't belong in a file name.
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// attachmentNames returns the file names of attachments for the output.
+func attachmentNames(attachments []provider.Attachment) []string {
+	var names []string
+	for _, a := range attachments {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// loadEndpoints reads OpenAI-compatible endpoint declarations from a JSON file
+// containing an array of provider.Endpoint objects.
+func loadEndpoints(path string) ([]provider.Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading endpoints file: %w", err)
+	}
+
+	var endpoints []provider.Endpoint
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("parsing endpoints file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, ep := range endpoints {
+		if ep.Name == "" || ep.BaseURL == "" {
+			return nil, fmt.Errorf("endpoints file: each endpoint needs a name and base_url")
+		}
+		switch ep.Name + ":" {
+		case provider.OllamaPrefix, provider.AzurePrefix, provider.BedrockPrefix, provider.VertexPrefix:
+			return nil, fmt.Errorf("endpoints file: endpoint name %q is reserved", ep.Name)
+		}
+		if seen[ep.Name] {
+			return nil, fmt.Errorf("endpoints file: duplicate endpoint %q", ep.Name)
+		}
+		seen[ep.Name] = true
+	}
+
+	return endpoints, nil
+}
+
+// registerOllama validates the requested "ollama:" models against the models
+// installed on the Ollama server and registers them with a shared provider.
+// Discovery is held to the connect timeout, since the server is local.
+func registerOllama(ctx context.Context, registry *provider.Registry, models []string, cfg *config) error {
+	p, err := provider.NewOllama(provider.WithOllamaHTTPClient(cfg.httpClient))
+	if err != nil {
+		return fmt.Errorf("initializing ollama provider: %w", err)
+	}
+
+	ctx, cancel := provider.WithTimeout(ctx, provider.TimeoutConnect, cfg.timeouts.Connect)
+	defer cancel()
+
+	installed, err := p.ListModels(ctx)
+	if err != nil {
+		err = provider.TimeoutCause(ctx, err)
+		return fmt.Errorf("discovering ollama models (is ollama running?): %w", err)
+	}
+
+	available := make(map[string]bool, len(installed))
+	for _, name := range installed {
+		available[name] = true
+		// "llama3" refers to "llama3:latest"
+		if base, ok := strings.CutSuffix(name, ":latest"); ok {
+			available[base] = true
+		}
+	}
+
+	for _, model := range models {
+		if !available[provider.OllamaModelName(model)] {
+			return fmt.Errorf("unknown model %q; installed ollama models: %v", model, installed)
+		}
+		registry.Register(model, p, middlewares(cfg, "ollama", model)...)
+	}
+
+	return nil
+}
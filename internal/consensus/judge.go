@@ -3,10 +3,12 @@ package consensus
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"text/template"
 
 	"github.com/johnayoung/llm-consensus/internal/provider"
+	"github.com/johnayoung/llm-consensus/internal/schema"
 )
 
 const judgePromptTemplate = `
@@ -14,13 +16,23 @@ Role
 You are an expert synthesis judge and careful editor. Your job is to combine multiple AI model responses into one best-possible answer to the user.
 
 Inputs
-User's original prompt:
+{{if .History}}Earlier conversation (context for the prompt below):
+{{range .History}}
+--- {{.Role}} ---
+{{.Content}}
+{{end}}
+{{end}}User's original prompt:
 {{.Prompt}}
 
 Model responses:
 {{range .Responses}}
 --- Model: {{.Model}} | Provider: {{.Provider}} ---
-{{.Content}}
+{{if .Incomplete}}[This response is incomplete ({{.IncompleteReason}}): it was cut off before the model finished. Use what it says, but don't read the missing part as disagreement.]
+{{end}}{{if and $.IncludeReasoning .Reasoning}}Reasoning:
+{{.Reasoning}}
+
+Final answer:
+{{end}}{{.Content}}
 
 {{end}}
 
@@ -41,14 +53,49 @@ Output Requirements
 - Do not quote or reference individual model responses.
 - Keep the answer coherent, non-redundant, and well-structured (use bullets/steps/headings if helpful).
 - Match formatting appropriate to the task (e.g., code blocks for code).
+{{- if .Structured}}
+- The model responses are JSON documents. Output ONE JSON document conforming to the same schema, with each field reconciled using the method above.
+{{- end}}
+`
+
+const tieBreakPromptTemplate = `
+Role
+You are an expert adjudicator. Several AI models answered the user's prompt with structured data and disagreed on some fields.
+
+User's original prompt:
+{{.Prompt}}
+
+Disputed fields and the values proposed for each:
+{{range .Fields}}
+Field: {{.Path}}
+{{range $i, $c := .Candidates}}  {{inc $i}}. {{json $c.Value}}
+{{end}}{{end}}
+Task
+For each field, choose the value most likely to be correct for the prompt.
+
+Output Requirements
+- Output ONLY a JSON object mapping each field path to the number of the chosen value, e.g. {"total": 2}.
 `
 
-var tmpl = template.Must(template.New("judge").Parse(judgePromptTemplate))
+var (
+	tmpl         = template.Must(template.New("judge").Parse(judgePromptTemplate))
+	tieBreakTmpl = template.Must(template.New("tiebreak").Funcs(template.FuncMap{
+		"inc": func(i int) int { return i + 1 },
+		"json": func(v any) string {
+			data, _ := json.Marshal(v)
+			return string(data)
+		},
+	}).Parse(tieBreakPromptTemplate))
+)
 
 // Judge synthesizes consensus from multiple model responses.
 type Judge struct {
-	provider provider.Provider
-	model    string
+	provider  provider.Provider
+	model     string
+	params    provider.Params
+	history   []provider.Message
+	reasoning bool
+	schema    *schema.Schema
 }
 
 // NewJudge creates a judge using the specified provider and model.
@@ -59,15 +106,49 @@ func NewJudge(p provider.Provider, model string) *Judge {
 	}
 }
 
+// WithParams sets generation parameters for the judge's own query.
+func (j *Judge) WithParams(p provider.Params) *Judge {
+	j.params = p
+	return j
+}
+
+// WithHistory sets the earlier conversation the prompt follows on from, so
+// the judge can interpret follow-up questions.
+func (j *Judge) WithHistory(history []provider.Message) *Judge {
+	j.history = history
+	return j
+}
+
+// WithReasoning sets whether the judge sees each model's reasoning alongside
+// its final answer. By default only final answers are shown.
+func (j *Judge) WithReasoning(include bool) *Judge {
+	j.reasoning = include
+	return j
+}
+
+// WithSchema requires the consensus to be JSON conforming to s, repaired
+// once if it doesn't validate.
+func (j *Judge) WithSchema(s *schema.Schema) *Judge {
+	j.schema = s
+	return j
+}
+
 // Synthesize generates a consensus response from multiple model outputs.
 func (j *Judge) Synthesize(ctx context.Context, originalPrompt string, responses []provider.Response) (string, error) {
-	return j.SynthesizeStream(ctx, originalPrompt, responses, nil)
+	resp, err := j.SynthesizeStream(ctx, originalPrompt, responses, nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
 }
 
 // SynthesizeStream generates a consensus response with streaming callback.
-func (j *Judge) SynthesizeStream(ctx context.Context, originalPrompt string, responses []provider.Response, callback provider.StreamCallback) (string, error) {
+// The returned Response carries the judge query's usage and finish reason;
+// when there is only one response to synthesize no query is made and only
+// Content is set.
+func (j *Judge) SynthesizeStream(ctx context.Context, originalPrompt string, responses []provider.Response, callback provider.StreamCallback) (provider.Response, error) {
 	if len(responses) == 0 {
-		return "", fmt.Errorf("no responses to synthesize")
+		return provider.Response{}, fmt.Errorf("no responses to synthesize")
 	}
 
 	// If only one response, return it directly (no consensus needed)
@@ -75,31 +156,108 @@ func (j *Judge) SynthesizeStream(ctx context.Context, originalPrompt string, res
 		if callback != nil {
 			callback(responses[0].Content)
 		}
-		return responses[0].Content, nil
+		return provider.Response{Content: responses[0].Content}, nil
 	}
 
 	// Build judge prompt
 	data := struct {
-		Prompt    string
-		Responses []provider.Response
+		History          []provider.Message
+		Prompt           string
+		Responses        []provider.Response
+		IncludeReasoning bool
+		Structured       bool
 	}{
-		Prompt:    originalPrompt,
-		Responses: responses,
+		History:          j.history,
+		Prompt:           originalPrompt,
+		Responses:        responses,
+		IncludeReasoning: j.reasoning,
+		Structured:       j.schema != nil,
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("executing template: %w", err)
+		return provider.Response{}, fmt.Errorf("executing template: %w", err)
 	}
 
 	// Query judge model with streaming
-	resp, err := j.provider.QueryStream(ctx, provider.Request{
+	req := provider.Request{
+		Model:  j.model,
+		Prompt: buf.String(),
+		Params: j.params,
+	}
+
+	var (
+		resp provider.Response
+		err  error
+	)
+	if j.schema != nil {
+		resp, err = j.schema.Query(ctx, j.provider, req, callback)
+	} else {
+		resp, err = j.provider.QueryStream(ctx, req, callback)
+	}
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("judge query failed: %w", err)
+	}
+
+	return resp, nil
+}
+
+// TieBreak asks the judge to settle the disputed fields of a vote. The
+// prompt shows only each field's competing values, not the full answers,
+// and the judge picks one by number. Chosen values are written back into
+// v. The returned Response carries the judge query's usage.
+func (j *Judge) TieBreak(ctx context.Context, originalPrompt string, v *Vote, callback provider.StreamCallback) (provider.Response, error) {
+	disputed := v.Disputed()
+	if len(disputed) == 0 {
+		return provider.Response{}, nil
+	}
+
+	data := struct {
+		Prompt string
+		Fields []Field
+	}{
+		Prompt: originalPrompt,
+		Fields: disputed,
+	}
+
+	var buf bytes.Buffer
+	if err := tieBreakTmpl.Execute(&buf, data); err != nil {
+		return provider.Response{}, fmt.Errorf("executing template: %w", err)
+	}
+
+	// Constrain the answer to one valid choice per field
+	props := make(map[string]any)
+	var required []string
+	for _, f := range disputed {
+		props[f.Path] = map[string]any{"type": "integer", "minimum": 1, "maximum": len(f.Candidates)}
+		required = append(required, f.Path)
+	}
+	raw, err := json.Marshal(map[string]any{"type": "object", "properties": props, "required": required})
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("building tie-break schema: %w", err)
+	}
+	choices, err := schema.Parse(raw)
+	if err != nil {
+		return provider.Response{}, err
+	}
+
+	resp, err := choices.Query(ctx, j.provider, provider.Request{
 		Model:  j.model,
 		Prompt: buf.String(),
+		Params: j.params,
 	}, callback)
 	if err != nil {
-		return "", fmt.Errorf("judge query failed: %w", err)
+		return resp, fmt.Errorf("judge tie-break failed: %w", err)
 	}
 
-	return resp.Content, nil
+	var picked map[string]float64
+	if err := json.Unmarshal([]byte(resp.Content), &picked); err != nil {
+		return resp, fmt.Errorf("parsing tie-break: %w", err)
+	}
+	for _, f := range disputed {
+		if n := int(picked[f.Path]); n >= 1 && n <= len(f.Candidates) {
+			v.Set(f.Path, f.Candidates[n-1].Value, "judge")
+		}
+	}
+	return resp, nil
 }
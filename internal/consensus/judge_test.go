@@ -134,3 +134,81 @@ func TestJudge_PromptTemplate(t *testing.T) {
 		}
 	}
 }
+
+func TestJudge_History(t *testing.T) {
+	var capturedPrompt string
+
+	p := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		capturedPrompt = req.Prompt
+		return provider.Response{Content: "consensus"}, nil
+	})
+
+	judge := NewJudge(p, "judge-model").WithHistory([]provider.Message{
+		{Role: provider.RoleUser, Content: "What is Raft?"},
+		{Role: provider.RoleAssistant, Content: "A consensus algorithm."},
+	})
+	responses := []provider.Response{
+		{Model: "model-a", Content: "answer a"},
+		{Model: "model-b", Content: "answer b"},
+	}
+
+	if _, err := judge.Synthesize(context.Background(), "How does it elect a leader?", responses); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, check := range []string{"What is Raft?", "A consensus algorithm.", "How does it elect a leader?"} {
+		if !strings.Contains(capturedPrompt, check) {
+			t.Errorf("prompt missing %q", check)
+		}
+	}
+}
+
+func TestJudge_Reasoning(t *testing.T) {
+	responses := []provider.Response{
+		{Model: "model-a", Content: "answer a", Reasoning: "thinking a"},
+		{Model: "model-b", Content: "answer b"},
+	}
+
+	for _, include := range []bool{false, true} {
+		var capturedPrompt string
+		p := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+			capturedPrompt = req.Prompt
+			return provider.Response{Content: "consensus"}, nil
+		})
+
+		judge := NewJudge(p, "judge-model").WithReasoning(include)
+		if _, err := judge.Synthesize(context.Background(), "prompt", responses); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := strings.Contains(capturedPrompt, "thinking a"); got != include {
+			t.Errorf("include=%v: prompt contains reasoning = %v", include, got)
+		}
+		if !strings.Contains(capturedPrompt, "answer a") {
+			t.Errorf("include=%v: prompt missing final answer", include)
+		}
+	}
+}
+
+func TestJudge_Incomplete(t *testing.T) {
+	var capturedPrompt string
+	p := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		capturedPrompt = req.Prompt
+		return provider.Response{Content: "consensus"}, nil
+	})
+
+	responses := []provider.Response{
+		{Model: "model-a", Content: "full answer"},
+		{Model: "model-b", Content: "half an ans", Incomplete: true, IncompleteReason: "timed out after 2m0s"},
+	}
+	if _, err := NewJudge(p, "judge-model").Synthesize(context.Background(), "prompt", responses); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := strings.Count(capturedPrompt, "incomplete"); n != 1 {
+		t.Errorf("got %d incomplete markers, want 1", n)
+	}
+	if !strings.Contains(capturedPrompt, "timed out after 2m0s") || !strings.Contains(capturedPrompt, "half an ans") {
+		t.Errorf("prompt missing the partial answer or its reason:\n%s", capturedPrompt)
+	}
+}
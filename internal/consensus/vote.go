@@ -0,0 +1,348 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+	"github.com/johnayoung/llm-consensus/internal/schema"
+)
+
+// Field is the outcome of voting on one field of the merged object.
+type Field struct {
+	Path string `json:"path"`
+	// Value is the winning value: the majority for scalars, the median for
+	// numbers and the union for arrays.
+	Value any `json:"value"`
+	// Agreement is the fraction of voting models whose value matches Value.
+	// For arrays a model matches when it lists exactly the items that a
+	// majority of models list.
+	Agreement float64  `json:"agreement"`
+	Dissent   []string `json:"dissenting_models,omitempty"`
+	// Support counts, for array fields, how many models list each item of
+	// Value, in order.
+	Support []int `json:"support,omitempty"`
+	// Candidates lists the competing values of a disputed field.
+	Candidates []Candidate `json:"candidates,omitempty"`
+	// ResolvedBy is "judge" when a tie-break chose Value.
+	ResolvedBy string `json:"resolved_by,omitempty"`
+
+	keys []string
+}
+
+// Candidate is one value proposed for a field and the models proposing it.
+type Candidate struct {
+	Value  any      `json:"value"`
+	Models []string `json:"models"`
+}
+
+// Disputed reports whether no strict majority of models agrees on the field.
+func (f Field) Disputed() bool {
+	return f.Agreement <= 0.5
+}
+
+// Vote is the result of a field-level vote.
+type Vote struct {
+	Value  map[string]any
+	Fields []Field
+	// Skipped lists models whose answer was not a JSON object.
+	Skipped []string
+}
+
+// Disputed returns the fields without a strict majority.
+func (v *Vote) Disputed() []Field {
+	var out []Field
+	for _, f := range v.Fields {
+		if f.Disputed() {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Set replaces the value of the field at path in both Fields and Value,
+// recording who resolved it.
+func (v *Vote) Set(path string, value any, resolvedBy string) bool {
+	for i := range v.Fields {
+		f := &v.Fields[i]
+		if f.Path != path {
+			continue
+		}
+		f.Value = value
+		f.ResolvedBy = resolvedBy
+		setPath(v.Value, f.keys, value)
+		return true
+	}
+	return false
+}
+
+// ballot is one model's parsed answer.
+type ballot struct {
+	model string
+	value map[string]any
+}
+
+// VoteFields merges JSON object answers field by field without an LLM.
+// Nested objects are walked; every other value is a leaf voted on as a
+// whole: numbers take the median, arrays the union of their items (with
+// per-item support counts), and other scalars the most common value, ties
+// going to the value seen first. A model that omits a field counts as
+// dissenting on it.
+func VoteFields(responses []provider.Response) (*Vote, error) {
+	var (
+		ballots []ballot
+		skipped []string
+	)
+	for _, r := range responses {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(schema.Extract(r.Content)), &obj); err != nil || obj == nil {
+			skipped = append(skipped, r.Model)
+			continue
+		}
+		ballots = append(ballots, ballot{model: r.Model, value: obj})
+	}
+	if len(ballots) == 0 {
+		return nil, errors.New("no responses are JSON objects")
+	}
+
+	v := &Vote{Value: make(map[string]any), Skipped: skipped}
+	v.walk(ballots, nil)
+	return v, nil
+}
+
+// walk votes on the fields of the objects at keys, recursing into fields
+// that every model answering them gives as an object.
+func (v *Vote) walk(ballots []ballot, keys []string) {
+	var names []string
+	for _, b := range ballots {
+		obj, _ := lookup(b.value, keys).(map[string]any)
+		for name := range obj {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := append(slices.Clone(keys), name)
+
+		var (
+			values  []any
+			models  []string
+			objects = 0
+		)
+		for _, b := range ballots {
+			obj, _ := lookup(b.value, keys).(map[string]any)
+			val, ok := obj[name]
+			if !ok {
+				continue
+			}
+			if _, isObj := val.(map[string]any); isObj {
+				objects++
+			}
+			values = append(values, val)
+			models = append(models, b.model)
+		}
+
+		if objects == len(values) {
+			setPath(v.Value, path, map[string]any{})
+			v.walk(ballots, path)
+			continue
+		}
+
+		f := voteLeaf(values, models, ballots)
+		f.Path = strings.Join(path, ".")
+		f.keys = path
+		setPath(v.Value, path, f.Value)
+		v.Fields = append(v.Fields, f)
+	}
+}
+
+// voteLeaf votes on one field. values[i] came from models[i]; ballots is
+// every model taking part, so that models omitting the field dissent.
+func voteLeaf(values []any, models []string, ballots []ballot) Field {
+	var f Field
+	switch {
+	case allOf[float64](values):
+		f = voteNumber(values, models)
+	case allOf[[]any](values):
+		f = voteArray(values, models)
+	default:
+		f = voteScalar(values, models)
+	}
+
+	for _, b := range ballots {
+		if !slices.Contains(models, b.model) {
+			f.Dissent = append(f.Dissent, b.model)
+		}
+	}
+	f.Agreement = float64(len(ballots)-len(f.Dissent)) / float64(len(ballots))
+	if f.Disputed() {
+		f.Candidates = candidates(values, models)
+	}
+	return f
+}
+
+// voteScalar takes the most common value.
+func voteScalar(values []any, models []string) Field {
+	groups := candidates(values, models)
+	best := groups[0]
+	for _, g := range groups[1:] {
+		if len(g.Models) > len(best.Models) {
+			best = g
+		}
+	}
+
+	f := Field{Value: best.Value}
+	for i, val := range values {
+		if !equalJSON(val, best.Value) {
+			f.Dissent = append(f.Dissent, models[i])
+		}
+	}
+	return f
+}
+
+// voteNumber takes the median. With an even count it takes the lower of
+// the two middle values, so the winner is a number some model returned.
+func voteNumber(values []any, models []string) Field {
+	nums := make([]float64, len(values))
+	for i, val := range values {
+		nums[i] = val.(float64)
+	}
+	sorted := slices.Clone(nums)
+	slices.Sort(sorted)
+
+	median := sorted[(len(sorted)-1)/2]
+
+	f := Field{Value: median}
+	for i, n := range nums {
+		if n != median {
+			f.Dissent = append(f.Dissent, models[i])
+		}
+	}
+	return f
+}
+
+// voteArray takes the union of the items, counting the models listing each.
+// A model agrees when it lists exactly the items a majority lists.
+func voteArray(values []any, models []string) Field {
+	var (
+		union   []any
+		support []int
+	)
+	for _, val := range values {
+		var seen []any
+		for _, item := range val.([]any) {
+			if slices.ContainsFunc(seen, func(s any) bool { return equalJSON(s, item) }) {
+				continue
+			}
+			seen = append(seen, item)
+
+			i := slices.IndexFunc(union, func(u any) bool { return equalJSON(u, item) })
+			if i < 0 {
+				union = append(union, item)
+				support = append(support, 0)
+				i = len(union) - 1
+			}
+			support[i]++
+		}
+	}
+
+	var majority []any
+	for i, item := range union {
+		if support[i]*2 > len(values) {
+			majority = append(majority, item)
+		}
+	}
+
+	f := Field{Value: union, Support: support}
+	if union == nil {
+		f.Value = []any{}
+	}
+	for i, val := range values {
+		if !sameSet(val.([]any), majority) {
+			f.Dissent = append(f.Dissent, models[i])
+		}
+	}
+	return f
+}
+
+// candidates groups values by equality, in order of first appearance.
+func candidates(values []any, models []string) []Candidate {
+	var out []Candidate
+	for i, val := range values {
+		j := slices.IndexFunc(out, func(c Candidate) bool { return equalJSON(c.Value, val) })
+		if j < 0 {
+			out = append(out, Candidate{Value: val})
+			j = len(out) - 1
+		}
+		out[j].Models = append(out[j].Models, models[i])
+	}
+	return out
+}
+
+func allOf[T any](values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(T); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSet reports whether a and b hold the same items, ignoring order and
+// duplicates.
+func sameSet(a, b []any) bool {
+	contains := func(list []any, item any) bool {
+		return slices.ContainsFunc(list, func(x any) bool { return equalJSON(x, item) })
+	}
+	for _, item := range a {
+		if !contains(b, item) {
+			return false
+		}
+	}
+	for _, item := range b {
+		if !contains(a, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalJSON compares decoded JSON values. encoding/json sorts map keys, so
+// equal values marshal identically.
+func equalJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// lookup returns the value at keys, or nil if there is none.
+func lookup(obj map[string]any, keys []string) any {
+	var cur any = obj
+	for _, k := range keys {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[k]
+	}
+	return cur
+}
+
+// setPath sets the value at keys, creating intermediate objects.
+func setPath(obj map[string]any, keys []string, value any) {
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := obj[k].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			obj[k] = next
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = value
+}
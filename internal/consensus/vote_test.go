@@ -0,0 +1,141 @@
+package consensus
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+func TestVoteFields(t *testing.T) {
+	responses := []provider.Response{
+		{Model: "model-a", Content: `{"vendor": "Acme", "total": 100, "items": ["bolts", "nuts"], "address": {"city": "Austin"}}`},
+		{Model: "model-b", Content: "```json\n{\"vendor\": \"Acme\", \"total\": 110, \"items\": [\"bolts\", \"nuts\", \"washers\"], \"address\": {\"city\": \"Austin\"}}\n```"},
+		{Model: "model-c", Content: `{"vendor": "ACME Inc", "total": 100, "items": ["nuts", "bolts"], "address": {"city": "Dallas"}}`},
+		{Model: "model-d", Content: "not json"},
+	}
+
+	v, err := VoteFields(responses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(v.Skipped, []string{"model-d"}) {
+		t.Errorf("got skipped %v, want [model-d]", v.Skipped)
+	}
+
+	fields := make(map[string]Field)
+	for _, f := range v.Fields {
+		fields[f.Path] = f
+	}
+
+	vendor := fields["vendor"]
+	if vendor.Value != "Acme" || !slices.Equal(vendor.Dissent, []string{"model-c"}) {
+		t.Errorf("vendor: got %v dissent %v", vendor.Value, vendor.Dissent)
+	}
+	if vendor.Agreement < 0.66 || vendor.Agreement > 0.67 {
+		t.Errorf("vendor: got agreement %v, want 2/3", vendor.Agreement)
+	}
+
+	if total := fields["total"]; total.Value != 100.0 || !slices.Equal(total.Dissent, []string{"model-b"}) {
+		t.Errorf("total: got median %v dissent %v", total.Value, total.Dissent)
+	}
+
+	items := fields["items"]
+	if got := items.Value.([]any); len(got) != 3 || got[2] != "washers" {
+		t.Errorf("items: got union %v", items.Value)
+	}
+	if !slices.Equal(items.Support, []int{3, 3, 1}) || !slices.Equal(items.Dissent, []string{"model-b"}) {
+		t.Errorf("items: got support %v dissent %v", items.Support, items.Dissent)
+	}
+
+	if city := fields["address.city"]; city.Value != "Austin" {
+		t.Errorf("address.city: got %v", city.Value)
+	}
+	if v.Value["address"].(map[string]any)["city"] != "Austin" {
+		t.Errorf("merged value not nested: %v", v.Value)
+	}
+	if len(v.Disputed()) != 0 {
+		t.Errorf("unexpected disputed fields: %+v", v.Disputed())
+	}
+}
+
+func TestVoteFields_EvenCount(t *testing.T) {
+	tests := []struct {
+		totals      []string
+		wantDissent []string
+	}{
+		{[]string{"20", "10"}, []string{"model-0"}},
+		{[]string{"10", "20", "20", "10"}, []string{"model-1", "model-2"}},
+	}
+
+	for _, tt := range tests {
+		var responses []provider.Response
+		for i, total := range tt.totals {
+			responses = append(responses, provider.Response{Model: "model-" + strconv.Itoa(i), Content: `{"total": ` + total + `}`})
+		}
+		v, err := VoteFields(responses)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		f := v.Fields[0]
+		if f.Value != 10.0 || f.Agreement != 0.5 || !slices.Equal(f.Dissent, tt.wantDissent) {
+			t.Errorf("%v: got %v with agreement %v, dissent %v; want the lower median 10", tt.totals, f.Value, f.Agreement, f.Dissent)
+		}
+	}
+}
+
+func TestVoteFields_MissingFieldDissents(t *testing.T) {
+	v, err := VoteFields([]provider.Response{
+		{Model: "model-a", Content: `{"po": "123"}`},
+		{Model: "model-b", Content: `{}`},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f := v.Fields[0]
+	if f.Agreement != 0.5 || !f.Disputed() || !slices.Equal(f.Dissent, []string{"model-b"}) {
+		t.Errorf("got %+v", f)
+	}
+}
+
+func TestJudge_TieBreak(t *testing.T) {
+	v, err := VoteFields([]provider.Response{
+		{Model: "model-a", Content: `{"currency": "USD", "vendor": "Acme"}`},
+		{Model: "model-b", Content: `{"currency": "EUR", "vendor": "Acme"}`},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	judge := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		if !strings.Contains(req.Prompt, "Field: currency") || strings.Contains(req.Prompt, "Field: vendor") {
+			t.Errorf("prompt should list only the disputed field:\n%s", req.Prompt)
+		}
+		if !strings.Contains(req.Prompt, `2. "EUR"`) {
+			t.Errorf("prompt missing candidate values:\n%s", req.Prompt)
+		}
+		if req.Schema == nil {
+			t.Error("tie-break should request structured output")
+		}
+		return provider.Response{Content: `{"currency": 2}`}, nil
+	})
+
+	if _, err := NewJudge(judge, "judge").TieBreak(context.Background(), "Extract the invoice", v, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v.Value["currency"] != "EUR" {
+		t.Errorf("got currency %v, want EUR", v.Value["currency"])
+	}
+	for _, f := range v.Fields {
+		if f.Path == "currency" && f.ResolvedBy != "judge" {
+			t.Errorf("currency not marked as resolved by judge: %+v", f)
+		}
+	}
+}
@@ -1,15 +1,30 @@
 package output
 
 import (
+	"github.com/johnayoung/llm-consensus/internal/consensus"
 	"github.com/johnayoung/llm-consensus/internal/provider"
+	"github.com/johnayoung/llm-consensus/internal/runner"
 )
 
 // Result is the JSON output structure for the CLI.
 type Result struct {
 	Prompt       string              `json:"prompt"`
+	History      []provider.Message  `json:"history,omitempty"`
+	Continues    string              `json:"continues,omitempty"`
+	Attachments  []string            `json:"attachments,omitempty"`
 	Responses    []provider.Response `json:"responses"`
-	Consensus    string              `json:"consensus"`
-	Judge        string              `json:"judge"`
+	Consensus    any                 `json:"consensus"` // string, or a JSON value with --schema or --strategy vote
+	Strategy     string              `json:"strategy,omitempty"`
+	Fields       []consensus.Field   `json:"fields,omitempty"`      // per-field votes with --strategy vote
+	Judge        string              `json:"judge,omitempty"`       // the judge that answered, if one ran
+	JudgeChain   []string            `json:"judge_chain,omitempty"` // judges tried in order, if more than one
+	JudgeUsage   provider.Usage      `json:"judge_usage,omitzero"`
+	JudgeFinish  string              `json:"judge_finish_reason,omitempty"`
+	Usage        provider.Usage      `json:"usage,omitzero"` // panel and judge combined
 	Warnings     []string            `json:"warnings,omitempty"`
+	Failures     []runner.Failure    `json:"failures,omitempty"` // why each failed model produced no answer
 	FailedModels []string            `json:"failed_models,omitempty"`
+	Skipped      []string            `json:"skipped_models,omitempty"`       // couldn't accept an attachment or circuit open
+	Chains       []runner.Chain      `json:"chains,omitempty"`               // which model of each fallback chain answered
+	Incomplete   []provider.Response `json:"incomplete_responses,omitempty"` // cut-off answers left out with --incomplete exclude
 }
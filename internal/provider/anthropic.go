@@ -1,15 +1,16 @@
 package provider
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 )
@@ -60,7 +61,7 @@ func NewAnthropic(opts ...AnthropicOption) (*Anthropic, error) {
 	a := &Anthropic{
 		apiKey:     apiKey,
 		baseURL:    "https://api.anthropic.com/v1",
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		httpClient: defaultHTTPClient,
 	}
 
 	for _, opt := range opts {
@@ -74,13 +75,7 @@ func NewAnthropic(opts ...AnthropicOption) (*Anthropic, error) {
 func (a *Anthropic) Query(ctx context.Context, req Request) (Response, error) {
 	start := time.Now()
 
-	payload := anthropicRequest{
-		Model:     req.Model,
-		MaxTokens: 4096,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: req.Prompt},
-		},
-	}
+	payload := buildAnthropicRequest(req, false)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -98,7 +93,7 @@ func (a *Anthropic) Query(ctx context.Context, req Request) (Response, error) {
 
 	resp, err := a.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
@@ -108,7 +103,7 @@ func (a *Anthropic) Query(ctx context.Context, req Request) (Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
 	var anthropicResp anthropicResponse
@@ -116,15 +111,46 @@ func (a *Anthropic) Query(ctx context.Context, req Request) (Response, error) {
 		return Response{}, fmt.Errorf("parsing response: %w", err)
 	}
 
-	if len(anthropicResp.Content) == 0 {
+	// With extended thinking on, thinking blocks precede the text blocks.
+	// Structured output arrives as the input of the schema tool call.
+	var (
+		content, reasoning strings.Builder
+		calls              []ToolCall
+		thinking           []ThinkingBlock
+	)
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			if req.Schema != nil && block.Name == schemaName {
+				content.Write(block.Input)
+				continue
+			}
+			calls = append(calls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		case "thinking":
+			reasoning.WriteString(block.Thinking)
+			thinking = append(thinking, ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			thinking = append(thinking, ThinkingBlock{Redacted: block.Data})
+		}
+	}
+	if content.Len() == 0 && len(calls) == 0 {
 		return Response{}, errors.New("no content in response")
 	}
 
 	return Response{
-		Model:    req.Model,
-		Content:  anthropicResp.Content[0].Text,
-		Provider: "anthropic",
-		Latency:  time.Since(start),
+		Model:         req.Model,
+		Content:       content.String(),
+		Reasoning:     reasoning.String(),
+		Provider:      "anthropic",
+		Latency:       time.Since(start),
+		Usage:         anthropicResp.Usage.usage(),
+		FinishReason:  anthropicResp.StopReason,
+		RequestID:     requestID(resp.Header, "request-id", anthropicResp.ID),
+		ResolvedModel: anthropicResp.Model,
+		ToolCalls:     calls,
+		Thinking:      thinking,
 	}, nil
 }
 
@@ -132,14 +158,7 @@ func (a *Anthropic) Query(ctx context.Context, req Request) (Response, error) {
 func (a *Anthropic) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
 	start := time.Now()
 
-	payload := anthropicStreamRequest{
-		Model:     req.Model,
-		MaxTokens: 4096,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: req.Prompt},
-		},
-		Stream: true,
-	}
+	payload := buildAnthropicRequest(req, true)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -157,78 +176,408 @@ func (a *Anthropic) QueryStream(ctx context.Context, req Request, callback Strea
 
 	resp, err := a.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
-	var fullContent strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+	result := Response{
+		Model:     req.Model,
+		Provider:  "anthropic",
+		RequestID: resp.Header.Get("request-id"),
+	}
+
+	var (
+		fullContent, reasoning strings.Builder
+		// Tool calls stream their input as JSON fragments, keyed by block
+		toolArgs  = make(map[int]*strings.Builder)
+		toolCalls []ToolCall
+		// Thinking blocks are kept whole, with their signatures, to be
+		// replayed with tool calls
+		thinking = make(map[int]*ThinkingBlock)
+	)
+	events := newSSEReader(resp.Body)
+	for done := false; !done; {
+		ev, err := events.Next()
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		// Overloads and other failures can arrive after the 200
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
 		}
-		data := strings.TrimPrefix(line, "data: ")
 
 		var event anthropicStreamEvent
-		if err := json.Unmarshal([]byte(data), &event); err != nil {
+		if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
 			continue
 		}
 
-		if event.Type == "content_block_delta" && event.Delta.Type == "text_delta" {
-			chunk := event.Delta.Text
-			fullContent.WriteString(chunk)
-			if callback != nil {
-				callback(chunk)
+		switch event.Type {
+		case "message_start":
+			// Input usage and the resolved model arrive up front
+			result.Usage = event.Message.Usage.usage()
+			result.ResolvedModel = event.Message.Model
+			if result.RequestID == "" {
+				result.RequestID = event.Message.ID
+			}
+		case "content_block_start":
+			block := event.ContentBlock
+			switch {
+			case block.Type == "tool_use" && (req.Schema == nil || block.Name != schemaName):
+				toolArgs[event.Index] = &strings.Builder{}
+				toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name})
+			case block.Type == "thinking":
+				thinking[event.Index] = &ThinkingBlock{}
+			case block.Type == "redacted_thinking":
+				thinking[event.Index] = &ThinkingBlock{Redacted: block.Data}
+			}
+		case "content_block_delta":
+			if args, ok := toolArgs[event.Index]; ok {
+				args.WriteString(event.Delta.PartialJSON)
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta", "input_json_delta":
+				chunk := event.Delta.Text + event.Delta.PartialJSON
+				fullContent.WriteString(chunk)
+				if callback != nil {
+					callback(chunk)
+				}
+			case "thinking_delta":
+				reasoning.WriteString(event.Delta.Thinking)
+				if b, ok := thinking[event.Index]; ok {
+					b.Thinking += event.Delta.Thinking
+				}
+			case "signature_delta":
+				if b, ok := thinking[event.Index]; ok {
+					b.Signature += event.Delta.Signature
+				}
 			}
+		case "message_delta":
+			// Output token counts in message_delta are cumulative
+			result.FinishReason = event.Delta.StopReason
+			result.Usage.OutputTokens = event.Usage.OutputTokens
+		case "message_stop":
+			done = true
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return Response{}, fmt.Errorf("reading stream: %w", err)
+	// Blocks start in index order, matching the order of toolCalls
+	indexes := make([]int, 0, len(toolArgs))
+	for i := range toolArgs {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	for n, i := range indexes {
+		toolCalls[n].Arguments = rawArguments(toolArgs[i].String())
+	}
+	blocks := make([]int, 0, len(thinking))
+	for i := range thinking {
+		blocks = append(blocks, i)
+	}
+	slices.Sort(blocks)
+	for _, i := range blocks {
+		result.Thinking = append(result.Thinking, *thinking[i])
 	}
 
-	return Response{
-		Model:    req.Model,
-		Content:  fullContent.String(),
-		Provider: "anthropic",
-		Latency:  time.Since(start),
-	}, nil
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.ToolCalls = toolCalls
+	result.Latency = time.Since(start)
+	return result, nil
 }
 
+// buildAnthropicRequest maps a Request onto the Messages API payload.
+// max_tokens is required by the API, so a generous default is used when unset.
+// System turns move to the top-level system field. The Messages API has no
+// seed parameter. A thinking budget enables extended thinking, with
+// temperature and top_p left out as the API requires; see
+// anthropicThinkingLimits for how it is fitted under max_tokens. A schema is enforced by forcing a tool whose input is the schema;
+// alongside other tools, any tool call is forced instead. The API rejects
+// forced tool use with thinking, so thinking is left off with a schema.
+// With thinking on, each tool call must be replayed after the signed
+// thinking that preceded it; tool turns without any, such as ones made with
+// thinking off, leave thinking off too.
+func buildAnthropicRequest(req Request, stream bool) anthropicRequest {
+	budget := req.thinkingBudget()
+	if req.Schema != nil {
+		budget = 0
+	}
+	for _, m := range req.ToolTurns {
+		if len(m.ToolCalls) > 0 && len(m.Thinking) == 0 {
+			budget = 0
+		}
+	}
+
+	maxTokens, budget := anthropicThinkingLimits(req.MaxTokens, budget)
+	if maxTokens == 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	temperature, topP := req.Temperature, req.TopP
+	if budget > 0 {
+		temperature, topP = nil, nil
+	}
+
+	system, turns := req.splitSystem()
+	messages := make([]anthropicMessage, 0, len(turns))
+	for _, m := range turns {
+		if len(m.Attachments) == 0 {
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+			continue
+		}
+
+		// Attachments go ahead of the text, as Anthropic recommends
+		var blocks []anthropicContentBlock
+		for _, a := range m.Attachments {
+			blockType := "document"
+			if isImage(a.MIMEType) {
+				blockType = "image"
+			}
+			blocks = append(blocks, anthropicContentBlock{
+				Type: blockType,
+				Source: &anthropicSource{
+					Type:      "base64",
+					MediaType: a.MIMEType,
+					Data:      base64.StdEncoding.EncodeToString(a.Data),
+				},
+			})
+		}
+		blocks = append(blocks, anthropicContentBlock{Type: "text", Text: m.Content})
+		messages = append(messages, anthropicMessage{Role: m.Role, Content: blocks})
+	}
+
+	// Tool calls are tool_use blocks; results go back as tool_result
+	// blocks in a user turn
+	for _, m := range req.ToolTurns {
+		var blocks []anthropicContentBlock
+		if budget > 0 {
+			for _, t := range m.Thinking {
+				if t.Redacted != "" {
+					blocks = append(blocks, anthropicContentBlock{Type: "redacted_thinking", Data: t.Redacted})
+					continue
+				}
+				blocks = append(blocks, anthropicContentBlock{Type: "thinking", Thinking: t.Thinking, Signature: t.Signature})
+			}
+		}
+		if m.Content != "" {
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: m.Content})
+		}
+		for _, c := range m.ToolCalls {
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: c.ID, Name: c.Name, Input: c.toolArguments()})
+		}
+		for _, r := range m.ToolResults {
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: r.CallID, Content: r.Content, IsError: r.IsError})
+		}
+		role := RoleAssistant
+		if m.Role == RoleTool {
+			role = RoleUser
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	var (
+		tools      []anthropicTool
+		toolChoice *anthropicToolChoice
+	)
+	for _, t := range req.Tools {
+		tools = append(tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+	}
+	if req.Schema != nil {
+		tools = append(tools, anthropicTool{
+			Name:        schemaName,
+			Description: "Respond with output that conforms to this schema.",
+			InputSchema: req.Schema,
+		})
+		toolChoice = &anthropicToolChoice{Type: "tool", Name: schemaName}
+		if len(req.Tools) > 0 && !req.NoToolCalls {
+			toolChoice = &anthropicToolChoice{Type: "any"}
+		}
+	} else if len(req.Tools) > 0 && req.NoToolCalls {
+		toolChoice = &anthropicToolChoice{Type: "none"}
+	}
+
+	return anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     maxTokens,
+		System:        system,
+		Temperature:   temperature,
+		TopP:          topP,
+		StopSequences: req.Stop,
+		Thinking:      anthropicThinkingConfig(budget),
+		Messages:      messages,
+		Tools:         tools,
+		ToolChoice:    toolChoice,
+		Stream:        stream,
+	}
+}
+
+// SupportsTools reports that Claude accepts tool declarations.
+func (a *Anthropic) SupportsTools(model string) bool {
+	return true
+}
+
+// SupportsAttachment reports whether Claude accepts the MIME type as an
+// image or document block.
+func (a *Anthropic) SupportsAttachment(model, mimeType string) bool {
+	return isImage(mimeType) || mimeType == "application/pdf"
+}
+
+// anthropicThinkingConfig returns the thinking setting for a budget, or nil
+// to leave extended thinking off.
+func anthropicThinkingConfig(budget int) *anthropicThinking {
+	if budget <= 0 {
+		return nil
+	}
+	return &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+}
+
+// anthropicThinkingLimits returns the max_tokens and thinking budget to
+// send. The budget counts toward max_tokens and must stay below it. When
+// max_tokens is unset the default is raised to leave room for the answer;
+// when it is set, a budget that doesn't fit is cut to half of it, and
+// thinking is left off if that falls under the API's minimum budget.
+func anthropicThinkingLimits(maxTokens, budget int) (int, int) {
+	switch {
+	case budget <= 0:
+		return maxTokens, 0
+	case maxTokens == 0:
+		return anthropicDefaultMaxTokens + budget, budget
+	case budget >= maxTokens:
+		budget = maxTokens / 2
+	}
+	if budget < anthropicMinThinkingBudget {
+		return maxTokens, 0
+	}
+	return maxTokens, budget
+}
+
+// anthropicMinThinkingBudget is the smallest budget_tokens the API accepts.
+const anthropicMinThinkingBudget = 1024
+
+// anthropicDefaultMaxTokens is used when Request.MaxTokens is unset. Current
+// Claude models accept at least this many output tokens.
+const anthropicDefaultMaxTokens = 16384
+
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	Messages  []anthropicMessage `json:"messages"`
+	Model         string               `json:"model"`
+	MaxTokens     int                  `json:"max_tokens"`
+	System        string               `json:"system,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Thinking      *anthropicThinking   `json:"thinking,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
-type anthropicStreamRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	Messages  []anthropicMessage `json:"messages"`
-	Stream    bool               `json:"stream"`
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
+// anthropicMessage content is a string, or []anthropicContentBlock when the
+// turn carries attachments.
 type anthropicMessage struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *anthropicSource `json:"source,omitempty"`
+
+	// tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// thinking and redacted_thinking blocks
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
 	Content []struct {
-		Text string `json:"text"`
+		Type      string          `json:"type"`
+		Text      string          `json:"text"`
+		Thinking  string          `json:"thinking"`
+		Signature string          `json:"signature"` // thinking blocks
+		Data      string          `json:"data"`      // redacted_thinking blocks
+		ID        string          `json:"id"`        // tool_use blocks
+		Name      string          `json:"name"`      // tool_use blocks
+		Input     json.RawMessage `json:"input"`     // tool_use blocks
 	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage converts to Usage. Anthropic's input_tokens excludes cache reads
+// and writes, so they are added back to give the full prompt size.
+func (u anthropicUsage) usage() Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		OutputTokens: u.OutputTokens,
+		CachedTokens: u.CacheReadInputTokens,
+	}
 }
 
 type anthropicStreamEvent struct {
-	Type  string `json:"type"`
-	Delta struct {
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	Message      anthropicResponse `json:"message"`
+	ContentBlock struct {
 		Type string `json:"type"`
-		Text string `json:"text"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Data string `json:"data"` // redacted_thinking blocks
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
 	} `json:"delta,omitempty"`
+	Usage anthropicUsage `json:"usage"`
 }
@@ -0,0 +1,293 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropic_QueryStreamMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("request-id", "req_123")
+		fmt.Fprint(w, "event: message_start\n"+
+			`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":1}}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`+"\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":42}}`+"\n\n"+
+			"event: message_stop\n"+
+			`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := a.QueryStream(context.Background(), Request{Model: "claude-sonnet-4-5", Prompt: "hi"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Usage{InputTokens: 100, OutputTokens: 42, CachedTokens: 90}
+	if resp.Usage != want {
+		t.Errorf("got usage %+v, want %+v", resp.Usage, want)
+	}
+	if resp.Content != "Hello" || resp.FinishReason != "max_tokens" || !resp.Truncated() {
+		t.Errorf("got content %q, finish reason %q", resp.Content, resp.FinishReason)
+	}
+	if resp.RequestID != "req_123" || resp.ResolvedModel != "claude-sonnet-4-5-20250929" {
+		t.Errorf("got request ID %q, resolved model %q", resp.RequestID, resp.ResolvedModel)
+	}
+}
+
+func TestAnthropic_QueryThinking(t *testing.T) {
+	var body anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"content":[{"type":"thinking","thinking":"Let me add.","signature":"sig"},{"type":"text","text":"4"}],"stop_reason":"end_turn"}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := Request{Model: "claude-sonnet-4-5", Prompt: "2+2?", Params: Params{ThinkingBudget: 2048}}
+	resp, err := a.Query(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "4" || resp.Reasoning != "Let me add." {
+		t.Errorf("got content %q, reasoning %q", resp.Content, resp.Reasoning)
+	}
+	if body.Thinking == nil || body.Thinking.BudgetTokens != 2048 || body.MaxTokens <= 2048 {
+		t.Errorf("got thinking %+v with max_tokens %d", body.Thinking, body.MaxTokens)
+	}
+}
+
+func TestAnthropic_QuerySchema(t *testing.T) {
+	var body anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"content":[{"type":"tool_use","id":"toolu_1","name":"response","input":{"answer":"4"}}],"stop_reason":"tool_use"}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schema := json.RawMessage(`{"type":"object","properties":{"answer":{"type":"string"}}}`)
+	req := Request{Model: "claude-sonnet-4-5", Prompt: "2+2?", Schema: schema, Params: Params{ThinkingBudget: 2048}}
+	resp, err := a.Query(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != `{"answer":"4"}` {
+		t.Errorf("got content %q", resp.Content)
+	}
+	if len(body.Tools) != 1 || body.ToolChoice == nil || body.ToolChoice.Name != body.Tools[0].Name {
+		t.Errorf("schema tool not forced: tools %+v, choice %+v", body.Tools, body.ToolChoice)
+	}
+	if body.Thinking != nil {
+		t.Error("thinking must be off when forcing a tool")
+	}
+}
+
+func TestBuildAnthropicRequest_TurnAttachments(t *testing.T) {
+	// A schema repair: the image stays on the turn that first sent it
+	req := Request{
+		Model: "claude-sonnet-4-5",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Extract the total", Attachments: []Attachment{{Name: "invoice.png", MIMEType: "image/png", Data: []byte("png")}}},
+			{Role: RoleAssistant, Content: "{}"},
+		},
+		Prompt: "Fix it",
+	}
+	body := buildAnthropicRequest(req, false)
+
+	if len(body.Messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(body.Messages))
+	}
+	blocks, ok := body.Messages[0].Content.([]anthropicContentBlock)
+	if !ok || len(blocks) != 2 || blocks[0].Type != "image" || blocks[1].Text != "Extract the total" {
+		t.Errorf("first turn lost its image: %+v", body.Messages[0].Content)
+	}
+	if body.Messages[2].Content != "Fix it" {
+		t.Errorf("got last turn %+v, want plain text", body.Messages[2].Content)
+	}
+}
+
+func TestBuildAnthropicRequest_ThinkingLimits(t *testing.T) {
+	temperature := 0.2
+	tests := []struct {
+		name          string
+		params        Params
+		wantMaxTokens int
+		wantBudget    int
+	}{
+		{"default max_tokens raised", Params{ReasoningEffort: "high"}, anthropicDefaultMaxTokens + 24576, 24576},
+		{"budget fits", Params{MaxTokens: 8192, ThinkingBudget: 2048}, 8192, 2048},
+		{"budget cut to half", Params{MaxTokens: 4096, ReasoningEffort: "high"}, 4096, 2048},
+		{"too small to think", Params{MaxTokens: 1500, ReasoningEffort: "high"}, 1500, 0},
+		{"no thinking", Params{MaxTokens: 4096}, 4096, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Temperature = &temperature
+			body := buildAnthropicRequest(Request{Model: "claude-sonnet-4-5", Prompt: "hi", Params: tt.params}, false)
+
+			var budget int
+			if body.Thinking != nil {
+				budget = body.Thinking.BudgetTokens
+			}
+			if body.MaxTokens != tt.wantMaxTokens || budget != tt.wantBudget {
+				t.Errorf("got max_tokens %d, budget %d; want %d, %d", body.MaxTokens, budget, tt.wantMaxTokens, tt.wantBudget)
+			}
+			if thinking := budget > 0; thinking != (body.Temperature == nil) {
+				t.Errorf("got temperature %v with budget %d; want it only without thinking", body.Temperature, budget)
+			}
+		})
+	}
+}
+
+func TestAnthropic_QueryStreamToolUse(t *testing.T) {
+	var body struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools []anthropicTool `json:"tools"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, "event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`+"\n\n"+
+			"event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"read_file","input":{}}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"go.mod\"}"}}`+"\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`+"\n\n"+
+			"event: message_stop\n"+
+			`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := Request{
+		Model:  "claude-sonnet-4-5",
+		Prompt: "What module is this?",
+		Tools:  []Tool{{Name: "read_file", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		ToolTurns: []Message{
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "toolu_1", Name: "list_files"}}},
+			{Role: RoleTool, ToolResults: []ToolResult{{CallID: "toolu_1", Name: "list_files", Content: "go.mod"}}},
+		},
+	}
+	resp, err := a.QueryStream(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "Checking." || len(resp.ToolCalls) != 1 {
+		t.Fatalf("got content %q, tool calls %+v", resp.Content, resp.ToolCalls)
+	}
+	if call := resp.ToolCalls[0]; call.ID != "toolu_2" || string(call.Arguments) != `{"path":"go.mod"}` {
+		t.Errorf("got tool call %+v", call)
+	}
+
+	if len(body.Tools) != 1 || len(body.Messages) != 3 {
+		t.Fatalf("got %d tools, %d messages", len(body.Tools), len(body.Messages))
+	}
+	if result := body.Messages[2]; result.Role != "user" || !strings.Contains(string(result.Content), `"tool_use_id":"toolu_1"`) {
+		t.Errorf("tool result not sent as a user turn: %s %s", result.Role, result.Content)
+	}
+}
+
+func TestAnthropic_ToolLoopThinking(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Read go.mod."}}`+"\n\n"+
+			"event: content_block_delta\n"+
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`+"\n\n"+
+			"event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"enc"}}`+"\n\n"+
+			"event: content_block_start\n"+
+			`data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`+"\n\n"+
+			"event: message_delta\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`+"\n\n"+
+			"event: message_stop\n"+
+			`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := Request{
+		Model:  "claude-sonnet-4-5",
+		Prompt: "What module is this?",
+		Tools:  []Tool{{Name: "read_file", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Params: Params{ThinkingBudget: 2048},
+	}
+	resp, err := a.QueryStream(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ThinkingBlock{{Thinking: "Read go.mod.", Signature: "sig"}, {Redacted: "enc"}}
+	if len(resp.Thinking) != 2 || resp.Thinking[0] != want[0] || resp.Thinking[1] != want[1] {
+		t.Fatalf("got thinking %+v, want %+v", resp.Thinking, want)
+	}
+
+	// The next step replays the signed thinking, so thinking stays on
+	req.ToolTurns = []Message{
+		{Role: RoleAssistant, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking},
+		{Role: RoleTool, ToolResults: []ToolResult{{CallID: "toolu_1", Name: "read_file", Content: "module x"}}},
+	}
+	req.NoToolCalls = true
+	body := buildAnthropicRequest(req, true)
+	if body.Thinking == nil {
+		t.Error("thinking turned off after a tool call")
+	}
+	if body.ToolChoice == nil || body.ToolChoice.Type != "none" {
+		t.Errorf("got tool choice %+v, want none", body.ToolChoice)
+	}
+	blocks, ok := body.Messages[1].Content.([]anthropicContentBlock)
+	if !ok || len(blocks) != 3 || blocks[0].Type != "thinking" || blocks[0].Signature != "sig" ||
+		blocks[1].Type != "redacted_thinking" || blocks[1].Data != "enc" || blocks[2].Type != "tool_use" {
+		t.Errorf("thinking not replayed ahead of the tool call: %+v", body.Messages[1].Content)
+	}
+
+	// Tool calls made without thinking can't be replayed with it
+	req.ToolTurns[0].Thinking = nil
+	if body := buildAnthropicRequest(req, true); body.Thinking != nil {
+		t.Error("thinking on with an unsigned tool call")
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Attachment is a file sent alongside the prompt.
+//
+// Text attachments (text/*) are inlined into the prompt, so every provider
+// accepts them. Other types (images, PDFs) are encoded natively by providers
+// that implement AttachmentSupporter.
+type Attachment struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"-"`
+}
+
+// IsText reports whether the attachment is inlined into the prompt as text.
+func (a Attachment) IsText() bool {
+	return strings.HasPrefix(a.MIMEType, "text/")
+}
+
+// dataURL returns the attachment as a base64 data: URL.
+func (a Attachment) dataURL() string {
+	return "data:" + a.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// AttachmentSupporter is implemented by providers that can send non-text
+// attachments.
+type AttachmentSupporter interface {
+	// SupportsAttachment reports whether the model accepts the MIME type.
+	SupportsAttachment(model, mimeType string) bool
+}
+
+// Accepts reports whether p can send an attachment of the given MIME type
+// to model. Text is always accepted because it is inlined into the prompt.
+func Accepts(p Provider, model, mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	if s, ok := p.(AttachmentSupporter); ok {
+		return s.SupportsAttachment(model, mimeType)
+	}
+	return false
+}
+
+// promptText returns the prompt with text attachments appended, each under
+// a header naming the file.
+func (r Request) promptText() string {
+	var b strings.Builder
+	b.WriteString(r.Prompt)
+	for _, a := range r.Attachments {
+		if !a.IsText() {
+			continue
+		}
+		b.WriteString("\n\n--- Attachment: " + a.Name + " ---\n")
+		b.Write(a.Data)
+	}
+	return b.String()
+}
+
+// binaryAttachments returns the attachments that providers encode natively.
+func (r Request) binaryAttachments() []Attachment {
+	var out []Attachment
+	for _, a := range r.Attachments {
+		if !a.IsText() {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// isImage reports whether mimeType is one of the image formats the major
+// vision APIs all accept.
+func isImage(mimeType string) bool {
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
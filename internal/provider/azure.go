@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Azure OpenAI
+// Docs: https://learn.microsoft.com/azure/ai-services/openai/how-to/responses
+//
+// Azure hosts OpenAI models behind per-resource endpoints. Requests name a
+// deployment rather than a model ID, so panel entries look like
+// "azure:<deployment>", e.g. azure:gpt-5-prod.
+
+// AzurePrefix marks a model name as an Azure OpenAI deployment.
+const AzurePrefix = "azure:"
+
+const defaultAzureAPIVersion = "2025-04-01-preview"
+
+// AzureOpenAI implements Provider for Azure OpenAI deployments.
+// It speaks the same Responses API as OpenAI, so it reuses the OpenAI
+// provider with Azure's URL layout and authentication.
+type AzureOpenAI struct {
+	*OpenAI
+
+	endpoint     string
+	apiVersion   string
+	apiKey       string
+	tokenCommand string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// AzureOption configures an AzureOpenAI provider.
+type AzureOption func(*AzureOpenAI)
+
+// WithAzureEndpoint sets the resource endpoint, e.g. https://myres.openai.azure.com.
+func WithAzureEndpoint(endpoint string) AzureOption {
+	return func(a *AzureOpenAI) { a.endpoint = endpoint }
+}
+
+// WithAzureAPIVersion sets the api-version query parameter.
+func WithAzureAPIVersion(version string) AzureOption {
+	return func(a *AzureOpenAI) { a.apiVersion = version }
+}
+
+// WithAzureTokenCommand sets a shell command that prints a bearer token,
+// e.g. "az account get-access-token --resource https://cognitiveservices.azure.com".
+// Used instead of an API key.
+func WithAzureTokenCommand(cmd string) AzureOption {
+	return func(a *AzureOpenAI) { a.tokenCommand = cmd }
+}
+
+// WithAzureHTTPClient sets a custom HTTP client.
+func WithAzureHTTPClient(c *http.Client) AzureOption {
+	return func(a *AzureOpenAI) { a.httpClient = c }
+}
+
+// NewAzureOpenAI creates an Azure OpenAI provider.
+// Reads AZURE_OPENAI_ENDPOINT, AZURE_OPENAI_API_VERSION, and either
+// AZURE_OPENAI_API_KEY or AZURE_OPENAI_TOKEN_COMMAND from the environment.
+func NewAzureOpenAI(opts ...AzureOption) (*AzureOpenAI, error) {
+	a := &AzureOpenAI{
+		OpenAI: &OpenAI{
+			httpClient: defaultHTTPClient,
+			name:       "azure",
+			prefix:     AzurePrefix,
+		},
+		endpoint:     os.Getenv("AZURE_OPENAI_ENDPOINT"),
+		apiVersion:   os.Getenv("AZURE_OPENAI_API_VERSION"),
+		apiKey:       os.Getenv("AZURE_OPENAI_API_KEY"),
+		tokenCommand: os.Getenv("AZURE_OPENAI_TOKEN_COMMAND"),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.endpoint == "" {
+		return nil, errors.New("AZURE_OPENAI_ENDPOINT environment variable required")
+	}
+	if a.apiKey == "" && a.tokenCommand == "" {
+		return nil, errors.New("AZURE_OPENAI_API_KEY or AZURE_OPENAI_TOKEN_COMMAND environment variable required")
+	}
+	if a.apiVersion == "" {
+		a.apiVersion = defaultAzureAPIVersion
+	}
+
+	a.baseURL = strings.TrimRight(a.endpoint, "/") + "/openai"
+	a.query = "?api-version=" + url.QueryEscape(a.apiVersion)
+	a.authorize = a.setAuth
+
+	return a, nil
+}
+
+// setAuth uses the api-key header when a key is configured, otherwise a
+// bearer token from the token command.
+func (a *AzureOpenAI) setAuth(ctx context.Context, r *http.Request) error {
+	if a.apiKey != "" {
+		r.Header.Set("api-key", a.apiKey)
+		return nil
+	}
+
+	token, err := a.bearerToken(ctx)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// bearerToken returns a cached token, running the token command when the
+// cached one is missing or about to expire.
+func (a *AzureOpenAI) bearerToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Until(a.tokenExpiry) > time.Minute {
+		return a.token, nil
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	out, err := exec.CommandContext(ctx, shell, flag, a.tokenCommand).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("token command failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+
+	token, expiry := parseAzureToken(out)
+	if token == "" {
+		return "", errors.New("token command printed no token")
+	}
+
+	a.token = token
+	a.tokenExpiry = expiry
+	return token, nil
+}
+
+// parseAzureToken accepts either a raw token or the JSON printed by
+// `az account get-access-token`. Raw tokens are cached for ten minutes.
+func parseAzureToken(out []byte) (string, time.Time) {
+	var azToken struct {
+		AccessToken string          `json:"accessToken"`
+		ExpiresOn   json.RawMessage `json:"expires_on"`
+	}
+	if err := json.Unmarshal(out, &azToken); err == nil && azToken.AccessToken != "" {
+		// expires_on is a Unix timestamp, as a number or a string
+		if secs, err := strconv.ParseInt(strings.Trim(string(azToken.ExpiresOn), `"`), 10, 64); err == nil {
+			return azToken.AccessToken, time.Unix(secs, 0)
+		}
+		return azToken.AccessToken, time.Now().Add(10 * time.Minute)
+	}
+
+	return strings.TrimSpace(string(out)), time.Now().Add(10 * time.Minute)
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const azureResponse = `{"id":"resp_1","model":"gpt-5","status":"completed","output":[{"type":"message","content":[{"type":"output_text","text":"4"}]}],"usage":{"input_tokens":5,"output_tokens":1}}`
+
+func TestAzureOpenAI_DeploymentURL(t *testing.T) {
+	var (
+		r    *http.Request
+		body responsesRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r = req
+		json.NewDecoder(req.Body).Decode(&body)
+		fmt.Fprint(w, azureResponse)
+	}))
+	defer srv.Close()
+
+	t.Setenv("AZURE_OPENAI_ENDPOINT", srv.URL+"/")
+	t.Setenv("AZURE_OPENAI_API_KEY", "key")
+	t.Setenv("AZURE_OPENAI_API_VERSION", "")
+	t.Setenv("AZURE_OPENAI_TOKEN_COMMAND", "")
+	a, err := NewAzureOpenAI()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := a.Query(context.Background(), Request{Model: "azure:gpt-5-prod", Prompt: "2+2?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.URL.Path != "/openai/responses" || r.URL.Query().Get("api-version") != defaultAzureAPIVersion {
+		t.Errorf("got URL %s", r.URL)
+	}
+	if r.Header.Get("api-key") != "key" || r.Header.Get("Authorization") != "" {
+		t.Errorf("got api-key %q, authorization %q", r.Header.Get("api-key"), r.Header.Get("Authorization"))
+	}
+	// The deployment is sent as the model
+	if body.Model != "gpt-5-prod" {
+		t.Errorf("got model %q, want the deployment name", body.Model)
+	}
+	if resp.Content != "4" || resp.Provider != "azure" || resp.ResolvedModel != "gpt-5" {
+		t.Errorf("got content %q, provider %q, resolved model %q", resp.Content, resp.Provider, resp.ResolvedModel)
+	}
+}
+
+func TestAzureOpenAI_TokenCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token command uses sh")
+	}
+
+	var auths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		fmt.Fprint(w, azureResponse)
+	}))
+	defer srv.Close()
+
+	// The command logs each run, so the test can tell cached tokens apart
+	runs := filepath.Join(t.TempDir(), "runs")
+	expires := time.Now().Add(time.Hour).Unix()
+	cmd := fmt.Sprintf(`echo run >> '%s'; printf '{"accessToken":"tok-1","expires_on":"%d"}'`, runs, expires)
+
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	a, err := NewAzureOpenAI(WithAzureEndpoint(srv.URL), WithAzureAPIVersion("2025-03-01-preview"), WithAzureTokenCommand(cmd))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for range 2 {
+		if _, err := a.Query(context.Background(), Request{Model: "azure:gpt-5-prod", Prompt: "hi"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(auths) != 2 || auths[0] != "Bearer tok-1" || auths[1] != "Bearer tok-1" {
+		t.Errorf("got authorization headers %q", auths)
+	}
+	if ran := countLines(t, runs); ran != 1 {
+		t.Errorf("token command ran %d times, want 1 with the token cached", ran)
+	}
+}
+
+func TestAzureOpenAI_TokenCommandFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token command uses sh")
+	}
+
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	a, err := NewAzureOpenAI(WithAzureEndpoint("http://127.0.0.1:1"), WithAzureTokenCommand("echo 'please run az login' >&2; exit 1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = a.Query(context.Background(), Request{Model: "azure:gpt-5-prod", Prompt: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "please run az login") {
+		t.Errorf("got %v, want the command's stderr", err)
+	}
+}
+
+func TestParseAzureToken(t *testing.T) {
+	tests := []struct {
+		name, out, want string
+		wantExpiry      time.Time
+	}{
+		{"raw", "tok-raw\n", "tok-raw", time.Time{}},
+		{"az string expiry", `{"accessToken":"tok-az","expires_on":"1767225600"}`, "tok-az", time.Unix(1767225600, 0)},
+		{"az number expiry", `{"accessToken":"tok-az","expires_on":1767225600}`, "tok-az", time.Unix(1767225600, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, expiry := parseAzureToken([]byte(tt.out))
+			if token != tt.want {
+				t.Errorf("got token %q, want %q", token, tt.want)
+			}
+			if !tt.wantExpiry.IsZero() && !expiry.Equal(tt.wantExpiry) {
+				t.Errorf("got expiry %v, want %v", expiry, tt.wantExpiry)
+			}
+		})
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.Count(string(data), "\n")
+}
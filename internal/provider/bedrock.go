@@ -0,0 +1,421 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWS Bedrock Models
+// Full list: https://docs.aws.amazon.com/bedrock/latest/userguide/models-supported.html
+//
+// Models are addressed as "bedrock:<model-id>" using Bedrock model IDs or
+// cross-region inference profile IDs, e.g.:
+//   - bedrock:anthropic.claude-sonnet-4-5-20250929-v1:0
+//   - bedrock:us.anthropic.claude-opus-4-5-20251101-v1:0
+//   - bedrock:meta.llama3-3-70b-instruct-v1:0
+//   - bedrock:amazon.nova-pro-v1:0
+
+// BedrockPrefix marks a model name as served by AWS Bedrock.
+const BedrockPrefix = "bedrock:"
+
+// Bedrock implements Provider for AWS Bedrock's Converse API.
+type Bedrock struct {
+	creds      awsCredentials
+	region     string
+	endpoint   string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// BedrockOption configures a Bedrock provider.
+type BedrockOption func(*Bedrock)
+
+// WithBedrockRegion sets the AWS region.
+func WithBedrockRegion(region string) BedrockOption {
+	return func(b *Bedrock) { b.region = region }
+}
+
+// WithBedrockEndpoint sets a custom endpoint (useful for local stubs or VPC endpoints).
+func WithBedrockEndpoint(url string) BedrockOption {
+	return func(b *Bedrock) { b.endpoint = url }
+}
+
+// WithBedrockHTTPClient sets a custom HTTP client.
+func WithBedrockHTTPClient(c *http.Client) BedrockOption {
+	return func(b *Bedrock) { b.httpClient = c }
+}
+
+// NewBedrock creates a Bedrock provider.
+// Reads credentials from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN
+// or the shared credentials file, and the region from AWS_REGION or the shared
+// config file. AWS_ENDPOINT_URL_BEDROCK_RUNTIME overrides the endpoint.
+func NewBedrock(opts ...BedrockOption) (*Bedrock, error) {
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bedrock{
+		creds:      creds,
+		region:     loadAWSRegion(),
+		endpoint:   os.Getenv("AWS_ENDPOINT_URL_BEDROCK_RUNTIME"),
+		httpClient: defaultHTTPClient,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.region == "" {
+		return nil, errors.New("AWS_REGION environment variable required")
+	}
+	if b.endpoint == "" {
+		b.endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", b.region)
+	}
+	b.endpoint = strings.TrimRight(b.endpoint, "/")
+
+	return b, nil
+}
+
+// Query sends a prompt to a Bedrock model using the Converse API.
+func (b *Bedrock) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildBedrockRequest(req)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := b.newRequest(ctx, req.Model, "converse", body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var converseResp bedrockConverseResponse
+	if err := json.Unmarshal(respBody, &converseResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Structured output arrives as the input of the forced tool call
+	var content, reasoning strings.Builder
+	for _, block := range converseResp.Output.Message.Content {
+		content.WriteString(block.Text)
+		if block.ToolUse != nil {
+			content.Write(block.ToolUse.Input)
+		}
+		if block.ReasoningContent != nil {
+			reasoning.WriteString(block.ReasoningContent.ReasoningText.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return Response{}, errors.New("no content in response")
+	}
+
+	return Response{
+		Model:        req.Model,
+		Content:      content.String(),
+		Reasoning:    reasoning.String(),
+		Provider:     "bedrock",
+		Latency:      time.Since(start),
+		Usage:        converseResp.Usage.usage(),
+		FinishReason: converseResp.StopReason,
+		RequestID:    resp.Header.Get("x-amzn-RequestId"),
+	}, nil
+}
+
+// QueryStream sends a prompt to a Bedrock model using ConverseStream.
+// The response body is AWS event stream framing rather than SSE.
+func (b *Bedrock) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildBedrockRequest(req)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := b.newRequest(ctx, req.Model, "converse-stream", body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Provider:  "bedrock",
+		RequestID: resp.Header.Get("x-amzn-RequestId"),
+	}
+
+	var fullContent, reasoning strings.Builder
+	// Usage arrives in a metadata event after messageStop
+	events := newEventStreamReader(resp.Body)
+	stopped := false
+	for {
+		msg, err := events.Next()
+		if errors.Is(err, io.EOF) && stopped {
+			break
+		}
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+
+		// Errors mid-stream arrive as exception messages
+		if msg.Headers[":message-type"] == "exception" {
+			var exc struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(msg.Payload, &exc) != nil || exc.Message == "" {
+				exc.Message = string(msg.Payload)
+			}
+			apiErr := streamError(msg.Headers[":exception-type"], "", exc.Message)
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		}
+
+		var event bedrockStreamEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			continue
+		}
+
+		switch msg.Headers[":event-type"] {
+		case "contentBlockDelta":
+			if chunk := event.Delta.Text + event.Delta.ToolUse.Input; chunk != "" {
+				fullContent.WriteString(chunk)
+				if callback != nil {
+					callback(chunk)
+				}
+			}
+			reasoning.WriteString(event.Delta.ReasoningContent.Text)
+		case "messageStop":
+			result.FinishReason = event.StopReason
+			stopped = true
+		case "metadata":
+			result.Usage = event.Usage.usage()
+		}
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// newRequest builds a SigV4-signed POST to /model/{modelId}/{action}.
+func (b *Bedrock) newRequest(ctx context.Context, model, action string, body []byte) (*http.Request, error) {
+	modelID := strings.TrimPrefix(model, BedrockPrefix)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	// Model IDs contain ':' which must be percent-encoded in the path
+	base := strings.TrimRight(httpReq.URL.EscapedPath(), "/")
+	httpReq.URL.Path = strings.TrimRight(httpReq.URL.Path, "/") + "/model/" + modelID + "/" + action
+	httpReq.URL.RawPath = base + "/model/" + awsURIEncode(modelID) + "/" + action
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	if action == "converse-stream" {
+		httpReq.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	}
+
+	signV4(httpReq, body, b.creds, b.region, "bedrock", b.now())
+	return httpReq, nil
+}
+
+// Converse API types
+// https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_Converse.html
+
+// buildBedrockRequest maps a Request onto the Converse payload.
+// System turns move to the top-level system field. Converse has no seed
+// parameter. Extended thinking is a model-specific field, so it is only
+// sent to Anthropic models, fitted under maxTokens as for the Anthropic API
+// and without temperature or top_p. A schema is enforced with a forced
+// tool, as for the Anthropic API, so it also turns thinking off.
+func buildBedrockRequest(req Request) bedrockConverseRequest {
+	system, turns := req.splitSystem()
+
+	var payload bedrockConverseRequest
+	for _, m := range turns {
+		payload.Messages = append(payload.Messages, bedrockMessage{
+			Role:    m.Role,
+			Content: []bedrockContentBlock{{Text: m.Content}},
+		})
+	}
+
+	if system != "" {
+		payload.System = []bedrockContentBlock{{Text: system}}
+	}
+
+	maxTokens, temperature, topP := req.MaxTokens, req.Temperature, req.TopP
+	if req.Schema == nil && strings.Contains(req.Model, "anthropic.") {
+		var budget int
+		maxTokens, budget = anthropicThinkingLimits(maxTokens, req.thinkingBudget())
+		if budget > 0 {
+			payload.AdditionalModelRequestFields = map[string]any{
+				"thinking": anthropicThinkingConfig(budget),
+			}
+			temperature, topP = nil, nil
+		}
+	}
+
+	if temperature != nil || topP != nil || maxTokens != 0 || len(req.Stop) > 0 {
+		payload.InferenceConfig = &bedrockInferenceConfig{
+			MaxTokens:     maxTokens,
+			Temperature:   temperature,
+			TopP:          topP,
+			StopSequences: req.Stop,
+		}
+	}
+
+	if req.Schema != nil {
+		var tool bedrockTool
+		tool.ToolSpec.Name = schemaName
+		tool.ToolSpec.Description = "Respond with output that conforms to this schema."
+		tool.ToolSpec.InputSchema.JSON = req.Schema
+		payload.ToolConfig = &bedrockToolConfig{Tools: []bedrockTool{tool}}
+		payload.ToolConfig.ToolChoice.Tool = &bedrockToolName{Name: schemaName}
+	}
+
+	return payload
+}
+
+type bedrockConverseRequest struct {
+	Messages        []bedrockMessage        `json:"messages"`
+	System          []bedrockContentBlock   `json:"system,omitempty"`
+	InferenceConfig *bedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *bedrockToolConfig      `json:"toolConfig,omitempty"`
+
+	AdditionalModelRequestFields map[string]any `json:"additionalModelRequestFields,omitempty"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type bedrockToolConfig struct {
+	Tools      []bedrockTool `json:"tools"`
+	ToolChoice struct {
+		Tool *bedrockToolName `json:"tool,omitempty"`
+	} `json:"toolChoice"`
+}
+
+type bedrockTool struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON json.RawMessage `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+type bedrockToolName struct {
+	Name string `json:"name"`
+}
+
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+type bedrockContentBlock struct {
+	Text             string                   `json:"text,omitempty"`
+	ReasoningContent *bedrockReasoningContent `json:"reasoningContent,omitempty"`
+	ToolUse          *bedrockToolUse          `json:"toolUse,omitempty"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type bedrockReasoningContent struct {
+	ReasoningText struct {
+		Text string `json:"text"`
+	} `json:"reasoningText"`
+}
+
+type bedrockConverseResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string       `json:"stopReason"`
+	Usage      bedrockUsage `json:"usage"`
+}
+
+type bedrockUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+}
+
+// usage converts to Usage. Like Anthropic, Bedrock's inputTokens excludes
+// cache reads and writes.
+func (u bedrockUsage) usage() Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + u.CacheReadInputTokens + u.CacheWriteInputTokens,
+		OutputTokens: u.OutputTokens,
+		CachedTokens: u.CacheReadInputTokens,
+	}
+}
+
+// bedrockStreamEvent covers the contentBlockDelta, messageStop and metadata
+// events of ConverseStream.
+type bedrockStreamEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+	Delta             struct {
+		Text             string `json:"text"`
+		ReasoningContent struct {
+			Text string `json:"text"`
+		} `json:"reasoningContent"`
+		ToolUse struct {
+			Input string `json:"input"` // partial JSON
+		} `json:"toolUse"`
+	} `json:"delta"`
+	StopReason string       `json:"stopReason"`
+	Usage      bedrockUsage `json:"usage"`
+}
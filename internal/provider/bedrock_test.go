@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// "get-vanilla" from the AWS SigV4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n  %s\nwant\n  %s", got, want)
+	}
+}
+
+func TestEventStreamReader(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(encodeEventStreamMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "contentBlockDelta",
+	}, []byte(`{"delta":{"text":"hi"}}`)))
+	buf.Write(encodeEventStreamMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "messageStop",
+	}, []byte(`{"stopReason":"end_turn"}`)))
+
+	r := newEventStreamReader(&buf)
+
+	msg, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Headers[":event-type"] != "contentBlockDelta" {
+		t.Errorf("got event type %q", msg.Headers[":event-type"])
+	}
+	if string(msg.Payload) != `{"delta":{"text":"hi"}}` {
+		t.Errorf("got payload %q", msg.Payload)
+	}
+
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Next(); err == nil {
+		t.Error("expected EOF after last message")
+	}
+}
+
+func TestEventStreamReader_Corrupt(t *testing.T) {
+	frame := encodeEventStreamMessage(map[string]string{":event-type": "x"}, []byte("{}"))
+	frame[len(frame)-5] ^= 0xff // flip a payload byte
+
+	if _, err := newEventStreamReader(bytes.NewReader(frame)).Next(); err == nil {
+		t.Error("expected checksum error")
+	}
+}
+
+func TestBedrock_QueryStream(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		w.Write(encodeEventStreamMessage(map[string]string{":message-type": "event", ":event-type": "contentBlockDelta"},
+			[]byte(`{"contentBlockIndex":0,"delta":{"text":"Hello"}}`)))
+		w.Write(encodeEventStreamMessage(map[string]string{":message-type": "event", ":event-type": "contentBlockDelta"},
+			[]byte(`{"contentBlockIndex":0,"delta":{"text":" world"}}`)))
+		w.Write(encodeEventStreamMessage(map[string]string{":message-type": "event", ":event-type": "messageStop"},
+			[]byte(`{"stopReason":"end_turn"}`)))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	b, err := NewBedrock(WithBedrockRegion("us-east-1"), WithBedrockEndpoint(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var chunks []string
+	resp, err := b.QueryStream(context.Background(), Request{
+		Model:  "bedrock:anthropic.claude-v2:1",
+		Prompt: "hi",
+	}, func(chunk string) { chunks = append(chunks, chunk) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "Hello world" {
+		t.Errorf("got content %q", resp.Content)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("got %d chunks, want 2", len(chunks))
+	}
+	if gotPath != "/model/anthropic.claude-v2%3A1/converse-stream" {
+		t.Errorf("got path %q", gotPath)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(gotAuth, "/us-east-1/bedrock/aws4_request") {
+		t.Errorf("got Authorization %q", gotAuth)
+	}
+}
+
+func TestBuildBedrockRequest_Thinking(t *testing.T) {
+	temperature := 0.2
+	req := Request{
+		Model:  "anthropic.claude-sonnet-4-5",
+		Prompt: "hi",
+		Params: Params{MaxTokens: 4096, Temperature: &temperature, ReasoningEffort: "high"},
+	}
+	body := buildBedrockRequest(req)
+
+	thinking, _ := body.AdditionalModelRequestFields["thinking"].(*anthropicThinking)
+	if thinking == nil || thinking.BudgetTokens != 2048 {
+		t.Errorf("got thinking %+v, want a budget of 2048", thinking)
+	}
+	if body.InferenceConfig == nil || body.InferenceConfig.MaxTokens != 4096 || body.InferenceConfig.Temperature != nil {
+		t.Errorf("got inference config %+v, want max tokens 4096 and no temperature", body.InferenceConfig)
+	}
+}
+
+// encodeEventStreamMessage builds one event stream frame with string headers.
+func encodeEventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var hdr bytes.Buffer
+	for name, value := range headers {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		binary.Write(&hdr, binary.BigEndian, uint16(len(value)))
+		hdr.WriteString(value)
+	}
+
+	total := uint32(eventStreamPreludeLen + hdr.Len() + len(payload) + 4)
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, total)
+	binary.Write(&msg, binary.BigEndian, uint32(hdr.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdr.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
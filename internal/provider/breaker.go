@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // requests pass
+	CircuitOpen     CircuitState = "open"      // requests fail fast until the cooldown ends
+	CircuitHalfOpen CircuitState = "half_open" // one probe request decides
+)
+
+// CircuitOpenError is returned without querying while a circuit is open.
+type CircuitOpenError struct {
+	Key   string // model name, or "provider:<name>"
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit open until " + e.Until.Local().Format("15:04")
+}
+
+// circuit is the persisted state of one breaker.
+type circuit struct {
+	State     CircuitState `json:"state"`
+	Failures  int          `json:"failures"` // consecutive, while closed
+	OpenUntil time.Time    `json:"open_until,omitzero"`
+
+	probing bool // a half-open probe is in flight in this process
+}
+
+// Breaker benches models and providers that keep failing. After threshold
+// consecutive outage failures (overloads, server and network errors, and
+// the model's own timeouts) a circuit opens and its requests fail fast with
+// a *CircuitOpenError. Once the cooldown passes it lets one probe through:
+// success closes it, failure opens it again. Each model has a circuit, as
+// does each provider, so an outage across a vendor benches all of its
+// models.
+//
+// State is saved to a JSON file so that consecutive CLI runs share it.
+type Breaker struct {
+	mu        sync.Mutex
+	path      string
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+	now       func() time.Time
+}
+
+// NewBreaker creates a breaker persisted at path, loading any saved state.
+// An empty path keeps state in memory only.
+func NewBreaker(path string, threshold int, cooldown time.Duration) (*Breaker, error) {
+	b := &Breaker{
+		path:      path,
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+		now:       time.Now,
+	}
+	if path == "" {
+		return b, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.circuits); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// State returns the state of the circuit for key, a model name or
+// "provider:<name>".
+func (b *Breaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[key]; ok {
+		return c.State
+	}
+	return CircuitClosed
+}
+
+// Middleware returns a middleware guarding model with its own circuit and
+// the circuit for providerName. Each query it sees counts once, so it
+// belongs outside any retries. A nil Breaker returns a nil middleware.
+func (b *Breaker) Middleware(providerName, model string) Middleware {
+	if b == nil {
+		return nil
+	}
+	keys := []string{"provider:" + providerName, model}
+	return func(next Provider) Provider {
+		return streamFunc(func(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+			if err := b.allow(keys); err != nil {
+				return Response{}, err
+			}
+			resp, err := next.QueryStream(ctx, req, callback)
+			b.record(keys, TimeoutCause(ctx, err))
+			return resp, err
+		})
+	}
+}
+
+// allow reports whether a request may pass every circuit in keys, moving
+// circuits whose cooldown has ended to half-open and claiming their probe.
+func (b *Breaker) allow(keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	for _, key := range keys {
+		c, ok := b.circuits[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case c.State == CircuitOpen && now.Before(c.OpenUntil):
+			return &CircuitOpenError{Key: key, Until: c.OpenUntil}
+		case c.State == CircuitOpen:
+			c.State = CircuitHalfOpen
+		}
+		if c.State == CircuitHalfOpen && c.probing {
+			// Another request is probing; wait for its verdict
+			return &CircuitOpenError{Key: key, Until: now.Add(b.cooldown)}
+		}
+	}
+	for _, key := range keys {
+		if c, ok := b.circuits[key]; ok && c.State == CircuitHalfOpen {
+			c.probing = true
+		}
+	}
+	return nil
+}
+
+// record updates the circuits in keys with the outcome of a request.
+func (b *Breaker) record(keys []string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changed := false
+	for _, key := range keys {
+		c, ok := b.circuits[key]
+		if !ok {
+			c = &circuit{State: CircuitClosed}
+		}
+		c.probing = false
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			// Says nothing about the vendor
+			continue
+		case !outage(err):
+			// The vendor answered, even if with an error about the request
+			if !ok {
+				continue
+			}
+			delete(b.circuits, key)
+		default:
+			b.circuits[key] = c
+			c.Failures++
+			if c.State == CircuitHalfOpen || c.Failures >= b.threshold {
+				c.State = CircuitOpen
+				c.OpenUntil = b.now().Add(b.cooldown)
+				c.Failures = 0
+			}
+		}
+		changed = true
+	}
+	if changed {
+		b.save()
+	}
+}
+
+// outage reports whether err suggests the vendor or model is unavailable,
+// as opposed to a problem with the request. Rate limits aren't outages:
+// the vendor is up, and only this caller's quota is spent. Nor is the run
+// timeout, or any other deadline of the caller's: it can end a query that
+// was going fine.
+func outage(err error) bool {
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return timeout.Limit != TimeoutRun
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable() && apiErr.Class != ErrorRateLimit
+}
+
+// save writes the circuits to disk. Failures are ignored: the breaker
+// still works in memory. Callers hold b.mu.
+func (b *Breaker) save() {
+	if b.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(b.circuits, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, b.path)
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "circuits.json")
+	b, err := NewBreaker(path, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	calls := 0
+	var next error
+	p := Chain(b.Middleware("anthropic", "claude"))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+		calls++
+		return Response{Content: "ok"}, next
+	}))
+	query := func() error {
+		_, err := p.Query(context.Background(), Request{Model: "claude"})
+		return err
+	}
+
+	// Request errors don't count as outages
+	next = &APIError{StatusCode: 400, Class: ErrorInvalidRequest}
+	query()
+	query()
+	if b.State("claude") != CircuitClosed {
+		t.Fatalf("opened on invalid requests")
+	}
+
+	next = &APIError{StatusCode: 529, Class: ErrorOverloaded}
+	query()
+	query()
+	if b.State("claude") != CircuitOpen || b.State("provider:anthropic") != CircuitOpen {
+		t.Fatalf("got states %s, %s after two outages", b.State("claude"), b.State("provider:anthropic"))
+	}
+
+	var open *CircuitOpenError
+	if err := query(); !errors.As(err, &open) || calls != 4 {
+		t.Fatalf("got %v after %d calls, want a fast circuit open error", err, calls)
+	}
+
+	// A new process sees the same state
+	reloaded, err := NewBreaker(path, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reloaded.State("claude") != CircuitOpen {
+		t.Errorf("state not persisted")
+	}
+
+	// Another model of the same provider is benched with it
+	other := Chain(b.Middleware("anthropic", "claude-haiku"))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+		t.Error("queried a model of a benched provider")
+		return Response{}, nil
+	}))
+	if _, err := other.Query(context.Background(), Request{}); !errors.As(err, &open) || open.Key != "provider:anthropic" {
+		t.Errorf("got %v, want provider circuit open", err)
+	}
+
+	// After the cooldown one probe goes through; a failure reopens
+	now = now.Add(time.Minute)
+	if err := query(); errors.As(err, &open) || calls != 5 {
+		t.Fatalf("probe not sent: %v", err)
+	}
+	if b.State("claude") != CircuitOpen {
+		t.Errorf("failed probe left state %s", b.State("claude"))
+	}
+
+	// A successful probe closes the circuit
+	now = now.Add(time.Minute)
+	next = nil
+	if err := query(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.State("claude") != CircuitClosed || b.State("provider:anthropic") != CircuitClosed {
+		t.Errorf("successful probe left states %s, %s", b.State("claude"), b.State("provider:anthropic"))
+	}
+}
+
+func TestBreaker_Timeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      TimeoutLimit // "" for a caller's own deadline
+		wantOutage bool
+	}{
+		{"per-model", TimeoutPerModel, true},
+		{"run", TimeoutRun, false},
+		{"caller deadline", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBreaker("", 1, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p := Chain(b.Middleware("anthropic", "claude"))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+				<-ctx.Done()
+				return Response{}, ctx.Err()
+			}))
+
+			var (
+				ctx    context.Context
+				cancel context.CancelFunc
+			)
+			if tt.limit != "" {
+				ctx, cancel = WithTimeout(context.Background(), tt.limit, 10*time.Millisecond)
+			} else {
+				ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+			}
+			defer cancel()
+			p.Query(ctx, Request{Model: "claude"})
+
+			if got := b.State("claude") == CircuitOpen; got != tt.wantOutage {
+				t.Errorf("got state %s, want outage %v", b.State("claude"), tt.wantOutage)
+			}
+		})
+	}
+}
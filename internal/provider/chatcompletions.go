@@ -0,0 +1,384 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAI-compatible Chat Completions servers
+// Spec: https://platform.openai.com/docs/api-reference/chat
+//
+// Many hosted and self-hosted servers implement /v1/chat/completions but not
+// the newer Responses API used by the OpenAI provider, e.g.:
+//   - DeepSeek             : https://api.deepseek.com/v1
+//   - Groq                 : https://api.groq.com/openai/v1
+//   - Together             : https://api.together.xyz/v1
+//   - vLLM                 : http://localhost:8000/v1
+//   - llama.cpp server     : http://localhost:8080/v1
+//   - LM Studio            : http://localhost:1234/v1
+//
+// Each server is declared as a named Endpoint and its models are addressed
+// as "<endpoint>:<model>", e.g. groq:llama-3.3-70b-versatile.
+
+// Endpoint declares a named OpenAI-compatible server.
+type Endpoint struct {
+	Name      string   `json:"name"`                  // prefix used to address models, e.g. "groq"
+	BaseURL   string   `json:"base_url"`              // URL up to and including /v1
+	APIKeyEnv string   `json:"api_key_env,omitempty"` // env var holding the key; empty for no auth
+	Models    []string `json:"models,omitempty"`      // models served; empty accepts any
+
+	// NoStreamUsage stops asking for token usage at the end of a stream,
+	// for servers that reject stream_options
+	NoStreamUsage bool `json:"no_stream_usage,omitempty"`
+}
+
+// ChatCompletions implements Provider for OpenAI-compatible Chat Completions APIs.
+type ChatCompletions struct {
+	name       string
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+
+	noStreamUsage bool
+}
+
+// ChatCompletionsOption configures a ChatCompletions provider.
+type ChatCompletionsOption func(*ChatCompletions)
+
+// WithChatCompletionsHTTPClient sets a custom HTTP client.
+func WithChatCompletionsHTTPClient(client *http.Client) ChatCompletionsOption {
+	return func(c *ChatCompletions) { c.httpClient = client }
+}
+
+// NewChatCompletions creates a provider for the given endpoint.
+// Reads the API key from the endpoint's APIKeyEnv variable, if one is set.
+func NewChatCompletions(ep Endpoint, opts ...ChatCompletionsOption) (*ChatCompletions, error) {
+	if ep.Name == "" {
+		return nil, errors.New("endpoint name required")
+	}
+	if ep.BaseURL == "" {
+		return nil, fmt.Errorf("endpoint %s: base_url required", ep.Name)
+	}
+
+	var apiKey string
+	if ep.APIKeyEnv != "" {
+		apiKey = os.Getenv(ep.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s environment variable required", ep.APIKeyEnv)
+		}
+	}
+
+	c := &ChatCompletions{
+		name:       ep.Name,
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(ep.BaseURL, "/"),
+		httpClient: defaultHTTPClient,
+
+		noStreamUsage: ep.NoStreamUsage,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Query sends a prompt to a Chat Completions model and returns the response.
+func (c *ChatCompletions) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildChatCompletionsRequest(c.modelName(req.Model), req, false)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	c.setHeaders(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var chatResp chatCompletionsResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 || chatResp.Choices[0].Message.Content == "" {
+		return Response{}, errors.New("no content in response")
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Content:   chatResp.Choices[0].Message.Content,
+		Reasoning: chatResp.Choices[0].Message.reasoning(),
+		Provider:  c.name,
+		RequestID: resp.Header.Get("x-request-id"),
+	}
+	chatResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// QueryStream sends a prompt to a Chat Completions model and streams the response.
+func (c *ChatCompletions) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildChatCompletionsRequest(c.modelName(req.Model), req, true)
+	if c.noStreamUsage {
+		payload.StreamOptions = nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	c.setHeaders(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Provider:  c.name,
+		RequestID: resp.Header.Get("x-request-id"),
+	}
+
+	var fullContent, reasoning strings.Builder
+	events := newSSEReader(resp.Body)
+	for {
+		ev, err := events.Next()
+		if errors.Is(err, io.EOF) && result.FinishReason != "" {
+			break // not every server sends [DONE]
+		}
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		if ev.Data == "[DONE]" {
+			break
+		}
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		}
+
+		var chunk chatCompletionsResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			continue
+		}
+
+		if len(chunk.Choices) > 0 {
+			delta := chunk.Choices[0].Delta
+			reasoning.WriteString(delta.reasoning())
+			if delta.Content != "" {
+				fullContent.WriteString(delta.Content)
+				if callback != nil {
+					callback(delta.Content)
+				}
+			}
+		}
+
+		chunk.fill(&result)
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// modelName strips the "<endpoint>:" prefix from a model name.
+func (c *ChatCompletions) modelName(model string) string {
+	return strings.TrimPrefix(model, c.name+":")
+}
+
+func (c *ChatCompletions) setHeaders(httpReq *http.Request) {
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// Chat Completions API types
+// https://platform.openai.com/docs/api-reference/chat/create
+
+// buildChatCompletionsRequest maps a Request onto the Chat Completions
+// payload. The system prompt becomes a leading system message.
+func buildChatCompletionsRequest(model string, req Request, stream bool) chatCompletionsRequest {
+	var messages []chatMessage
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Conversation() {
+		messages = append(messages, chatMessage{Role: m.Role, Content: m.Content})
+	}
+
+	payload := chatCompletionsRequest{
+		Model:           model,
+		Messages:        messages,
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		MaxTokens:       req.MaxTokens,
+		Stop:            req.Stop,
+		Seed:            req.Seed,
+		ReasoningEffort: req.ReasoningEffort,
+		ResponseFormat:  chatSchemaFormat(req.Schema),
+		Stream:          stream,
+	}
+	if stream {
+		// Ask for a final chunk carrying token usage
+		payload.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+	}
+	return payload
+}
+
+type chatCompletionsRequest struct {
+	Model           string              `json:"model"`
+	Messages        []chatMessage       `json:"messages"`
+	Temperature     *float64            `json:"temperature,omitempty"`
+	TopP            *float64            `json:"top_p,omitempty"`
+	MaxTokens       int                 `json:"max_tokens,omitempty"`
+	Stop            []string            `json:"stop,omitempty"`
+	Seed            *int64              `json:"seed,omitempty"`
+	ReasoningEffort string              `json:"reasoning_effort,omitempty"`
+	ResponseFormat  *chatResponseFormat `json:"response_format,omitempty"`
+	Stream          bool                `json:"stream,omitempty"`
+	StreamOptions   *chatStreamOptions  `json:"stream_options,omitempty"`
+}
+
+// chatResponseFormat is the json_schema response_format shared by
+// OpenAI-compatible servers and Mistral.
+type chatResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+// chatSchemaFormat returns the response_format for a schema, or nil when
+// none is set.
+func chatSchemaFormat(schema json.RawMessage) *chatResponseFormat {
+	if schema == nil {
+		return nil
+	}
+	f := &chatResponseFormat{Type: "json_schema"}
+	f.JSONSchema.Name = schemaName
+	f.JSONSchema.Schema = schema
+	return f
+}
+
+type chatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionsResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      chatResponseMessage `json:"message"`
+		Delta        chatResponseMessage `json:"delta"`
+		FinishReason string              `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatCompletionsUsage `json:"usage"`
+}
+
+// chatResponseMessage is an assistant message or stream delta. Servers that
+// expose reasoning use one of two non-standard fields: "reasoning_content"
+// (DeepSeek, vLLM) or "reasoning" (OpenRouter, Groq).
+type chatResponseMessage struct {
+	Role             string `json:"role"`
+	Content          string `json:"content"`
+	Reasoning        string `json:"reasoning"`
+	ReasoningContent string `json:"reasoning_content"`
+}
+
+func (m chatResponseMessage) reasoning() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
+}
+
+type chatCompletionsUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u chatCompletionsUsage) usage() Usage {
+	return Usage{
+		InputTokens:     u.PromptTokens,
+		OutputTokens:    u.CompletionTokens,
+		ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens,
+		CachedTokens:    u.PromptTokensDetails.CachedTokens,
+	}
+}
+
+// fill copies the metadata present in r onto resp. When streaming, the
+// finish reason and usage arrive in the last chunks, so fields are
+// overwritten only when set.
+func (r *chatCompletionsResponse) fill(resp *Response) {
+	if r.Usage != nil {
+		resp.Usage = r.Usage.usage()
+	}
+	if len(r.Choices) > 0 && r.Choices[0].FinishReason != "" {
+		resp.FinishReason = r.Choices[0].FinishReason
+	}
+	if r.Model != "" {
+		resp.ResolvedModel = r.Model
+	}
+	if resp.RequestID == "" {
+		resp.RequestID = r.ID
+	}
+}
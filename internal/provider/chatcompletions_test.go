@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChatCompletions_Query(t *testing.T) {
+	var (
+		body chatCompletionsRequest
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("got path %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("x-request-id", "req_1")
+		fmt.Fprint(w, `{"id":"chatcmpl-1","model":"llama-3.3-70b","choices":[{"message":{"role":"assistant","content":"4","reasoning_content":"2+2"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":12,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":8},"completion_tokens_details":{"reasoning_tokens":3}}}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv("GROQ_API_KEY", "test")
+	c, err := NewChatCompletions(Endpoint{Name: "groq", BaseURL: srv.URL + "/v1/", APIKeyEnv: "GROQ_API_KEY"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	temp := 0.5
+	req := Request{
+		Model:    "groq:llama-3.3-70b",
+		Messages: []Message{{Role: RoleUser, Content: "1+1?"}, {Role: RoleAssistant, Content: "2"}},
+		Prompt:   "2+2?",
+		Params:   Params{System: "Be brief.", Temperature: &temp, MaxTokens: 100, Stop: []string{"\n"}},
+	}
+	resp, err := c.Query(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if auth != "Bearer test" {
+		t.Errorf("got authorization %q", auth)
+	}
+	if body.Model != "llama-3.3-70b" || body.Stream || body.StreamOptions != nil {
+		t.Errorf("got model %q, stream %v, stream options %+v", body.Model, body.Stream, body.StreamOptions)
+	}
+	wantRoles := []string{"system", "user", "assistant", "user"}
+	if len(body.Messages) != len(wantRoles) {
+		t.Fatalf("got %d messages, want %d", len(body.Messages), len(wantRoles))
+	}
+	for i, role := range wantRoles {
+		if body.Messages[i].Role != role {
+			t.Errorf("message %d: got role %q, want %q", i, body.Messages[i].Role, role)
+		}
+	}
+	if body.Messages[3].Content != "2+2?" || body.Temperature == nil || *body.Temperature != 0.5 || body.MaxTokens != 100 || len(body.Stop) != 1 {
+		t.Errorf("params not mapped: %+v", body)
+	}
+
+	if resp.Content != "4" || resp.Reasoning != "2+2" || resp.FinishReason != "stop" {
+		t.Errorf("got content %q, reasoning %q, finish reason %q", resp.Content, resp.Reasoning, resp.FinishReason)
+	}
+	want := Usage{InputTokens: 12, OutputTokens: 5, ReasoningTokens: 3, CachedTokens: 8}
+	if resp.Usage != want {
+		t.Errorf("got usage %+v, want %+v", resp.Usage, want)
+	}
+	if resp.Provider != "groq" || resp.RequestID != "req_1" || resp.ResolvedModel != "llama-3.3-70b" {
+		t.Errorf("got provider %q, request ID %q, resolved model %q", resp.Provider, resp.RequestID, resp.ResolvedModel)
+	}
+}
+
+func TestChatCompletions_QueryStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		ep            Endpoint
+		events        string
+		wantUsageOpts bool
+	}{
+		{
+			name: "usage chunk and done",
+			ep:   Endpoint{Name: "vllm"},
+			events: `data: {"id":"chatcmpl-1","model":"qwen3","choices":[{"delta":{"role":"assistant","reasoning":"Adding."}}]}` + "\n\n" +
+				`data: {"id":"chatcmpl-1","choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" +
+				`data: {"id":"chatcmpl-1","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n" +
+				`data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2}}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantUsageOpts: true,
+		},
+		{
+			name: "no done after the finish reason",
+			ep:   Endpoint{Name: "vllm", NoStreamUsage: true},
+			events: `data: {"id":"chatcmpl-1","model":"qwen3","choices":[{"delta":{"role":"assistant","reasoning":"Adding."}}]}` + "\n\n" +
+				`data: {"id":"chatcmpl-1","choices":[{"delta":{"content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":2}}` + "\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body chatCompletionsRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				fmt.Fprint(w, tt.events)
+			}))
+			defer srv.Close()
+
+			tt.ep.BaseURL = srv.URL
+			c, err := NewChatCompletions(tt.ep)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var streamed string
+			resp, err := c.QueryStream(context.Background(), Request{Model: "vllm:qwen3", Prompt: "hi"}, func(chunk string) {
+				streamed += chunk
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !body.Stream || (body.StreamOptions != nil) != tt.wantUsageOpts {
+				t.Errorf("got stream %v, stream options %+v", body.Stream, body.StreamOptions)
+			}
+			if resp.Content != "Hello" || streamed != "Hello" || resp.Reasoning != "Adding." {
+				t.Errorf("got content %q, streamed %q, reasoning %q", resp.Content, streamed, resp.Reasoning)
+			}
+			if resp.Usage != (Usage{InputTokens: 7, OutputTokens: 2}) || resp.FinishReason != "stop" {
+				t.Errorf("got usage %+v, finish reason %q", resp.Usage, resp.FinishReason)
+			}
+			if resp.RequestID != "chatcmpl-1" || resp.ResolvedModel != "qwen3" {
+				t.Errorf("got request ID %q, resolved model %q", resp.RequestID, resp.ResolvedModel)
+			}
+		})
+	}
+}
+
+func TestChatCompletions_StreamCutOff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `data: {"id":"chatcmpl-1","choices":[{"delta":{"content":"Hel"}}]}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	c, err := NewChatCompletions(Endpoint{Name: "vllm", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A stream that ends with neither [DONE] nor a finish reason was cut off
+	if _, err := c.QueryStream(context.Background(), Request{Model: "vllm:qwen3", Prompt: "hi"}, nil); err == nil {
+		t.Error("expected an error for a truncated stream")
+	}
+}
@@ -0,0 +1,328 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Cohere Models
+// Full list: https://docs.cohere.com/docs/models
+//
+// Command:
+//   - command-a-03-2025          : Most performant, agentic and multilingual
+//   - command-r-plus             : Complex RAG and multi-step tool use
+//   - command-r                  : Balanced RAG and tool use
+//   - command-r7b-12-2024        : Small, fast model
+
+// Cohere implements Provider for Cohere's v2 chat API.
+type Cohere struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// CohereOption configures a Cohere provider.
+type CohereOption func(*Cohere)
+
+// WithCohereBaseURL sets a custom base URL.
+func WithCohereBaseURL(url string) CohereOption {
+	return func(c *Cohere) { c.baseURL = url }
+}
+
+// WithCohereHTTPClient sets a custom HTTP client.
+func WithCohereHTTPClient(client *http.Client) CohereOption {
+	return func(c *Cohere) { c.httpClient = client }
+}
+
+// NewCohere creates a Cohere provider.
+// Reads API key from COHERE_API_KEY (or CO_API_KEY) environment variable.
+func NewCohere(opts ...CohereOption) (*Cohere, error) {
+	apiKey := os.Getenv("COHERE_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("CO_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, errors.New("COHERE_API_KEY environment variable required")
+	}
+
+	c := &Cohere{
+		apiKey:     apiKey,
+		baseURL:    "https://api.cohere.com/v2",
+		httpClient: defaultHTTPClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Query sends a prompt to a Cohere model and returns the response.
+func (c *Cohere) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildCohereRequest(req, false)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var cohereResp cohereResponse
+	if err := json.Unmarshal(respBody, &cohereResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	var content, reasoning strings.Builder
+	for _, block := range cohereResp.Message.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "thinking":
+			reasoning.WriteString(block.Thinking)
+		}
+	}
+	if content.Len() == 0 {
+		return Response{}, errors.New("no content in response")
+	}
+
+	return Response{
+		Model:        req.Model,
+		Content:      content.String(),
+		Reasoning:    reasoning.String(),
+		Provider:     "cohere",
+		Latency:      time.Since(start),
+		Usage:        cohereResp.Usage.usage(),
+		FinishReason: cohereResp.FinishReason,
+		RequestID:    cohereResp.ID,
+	}, nil
+}
+
+// QueryStream sends a prompt to a Cohere model and streams the response.
+// Cohere's v2 stream uses typed events (message-start, content-delta,
+// message-end, ...) rather than OpenAI-style choice deltas.
+func (c *Cohere) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildCohereRequest(req, true)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:    req.Model,
+		Provider: "cohere",
+	}
+
+	var fullContent, reasoning strings.Builder
+	events := newSSEReader(resp.Body)
+	for done := false; !done; {
+		ev, err := events.Next()
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		}
+
+		var event cohereStreamEvent
+		if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+			continue
+		}
+
+		if event.Type == "message-start" {
+			result.RequestID = event.ID
+		}
+
+		if event.Type == "content-delta" {
+			reasoning.WriteString(event.Delta.Message.Content.Thinking)
+		}
+
+		if event.Type == "content-delta" && event.Delta.Message.Content.Text != "" {
+			chunk := event.Delta.Message.Content.Text
+			fullContent.WriteString(chunk)
+			if callback != nil {
+				callback(chunk)
+			}
+		}
+
+		if event.Type == "message-end" {
+			result.FinishReason = event.Delta.FinishReason
+			result.Usage = event.Delta.Usage.usage()
+			done = true
+		}
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// Cohere v2 chat API types
+// https://docs.cohere.com/reference/chat
+
+// buildCohereRequest maps a Request onto the v2 chat payload.
+// Cohere names top_p "p". Reasoning models take a thinking token budget.
+func buildCohereRequest(req Request, stream bool) cohereRequest {
+	var messages []cohereMessage
+	if req.System != "" {
+		messages = append(messages, cohereMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Conversation() {
+		messages = append(messages, cohereMessage{Role: m.Role, Content: m.Content})
+	}
+
+	var thinking *cohereThinking
+	switch {
+	case req.ReasoningEffort == "none":
+		thinking = &cohereThinking{Type: "disabled"}
+	case req.reasoning():
+		thinking = &cohereThinking{Type: "enabled", TokenBudget: req.thinkingBudget()}
+	}
+
+	payload := cohereRequest{
+		Model:         req.Model,
+		Messages:      messages,
+		Temperature:   req.Temperature,
+		P:             req.TopP,
+		MaxTokens:     req.MaxTokens,
+		StopSequences: req.Stop,
+		Seed:          req.Seed,
+		Thinking:      thinking,
+		Stream:        stream,
+	}
+	if req.Schema != nil {
+		payload.ResponseFormat = &cohereResponseFormat{Type: "json_object", JSONSchema: req.Schema}
+	}
+	return payload
+}
+
+type cohereRequest struct {
+	Model          string                `json:"model"`
+	Messages       []cohereMessage       `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	P              *float64              `json:"p,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	StopSequences  []string              `json:"stop_sequences,omitempty"`
+	Seed           *int64                `json:"seed,omitempty"`
+	Thinking       *cohereThinking       `json:"thinking,omitempty"`
+	ResponseFormat *cohereResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+}
+
+type cohereResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+type cohereThinking struct {
+	Type        string `json:"type"`
+	TokenBudget int    `json:"token_budget,omitempty"`
+}
+
+type cohereMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type cohereResponse struct {
+	ID           string `json:"id"`
+	FinishReason string `json:"finish_reason"`
+	Message      struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			Thinking string `json:"thinking"`
+		} `json:"content"`
+	} `json:"message"`
+	Usage cohereUsage `json:"usage"`
+}
+
+// cohereUsage carries billed and actual token counts. The actual counts
+// include the prompt template Cohere wraps around the conversation.
+type cohereUsage struct {
+	Tokens struct {
+		InputTokens  float64 `json:"input_tokens"`
+		OutputTokens float64 `json:"output_tokens"`
+	} `json:"tokens"`
+}
+
+func (u cohereUsage) usage() Usage {
+	return Usage{
+		InputTokens:  int(u.Tokens.InputTokens),
+		OutputTokens: int(u.Tokens.OutputTokens),
+	}
+}
+
+type cohereStreamEvent struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Delta struct {
+		Message struct {
+			Content struct {
+				Text     string `json:"text"`
+				Thinking string `json:"thinking"`
+			} `json:"content"`
+		} `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+		Usage        cohereUsage `json:"usage"`
+	} `json:"delta"`
+}
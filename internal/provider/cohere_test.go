@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCohere_QueryStream(t *testing.T) {
+	var body cohereRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat" || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("got path %s, accept %q", r.URL.Path, r.Header.Get("Accept"))
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, "event: message-start\n"+
+			`data: {"type":"message-start","id":"gen-1","delta":{"message":{"role":"assistant"}}}`+"\n\n"+
+			"event: content-start\n"+
+			`data: {"type":"content-start","index":0,"delta":{"message":{"content":{"type":"thinking","thinking":""}}}}`+"\n\n"+
+			"event: content-delta\n"+
+			`data: {"type":"content-delta","index":0,"delta":{"message":{"content":{"thinking":"Adding."}}}}`+"\n\n"+
+			"event: content-delta\n"+
+			`data: {"type":"content-delta","index":1,"delta":{"message":{"content":{"text":"Hel"}}}}`+"\n\n"+
+			"event: content-delta\n"+
+			`data: {"type":"content-delta","index":1,"delta":{"message":{"content":{"text":"lo"}}}}`+"\n\n"+
+			"event: message-end\n"+
+			`data: {"type":"message-end","delta":{"finish_reason":"MAX_TOKENS","usage":{"billed_units":{"input_tokens":4,"output_tokens":3},"tokens":{"input_tokens":210,"output_tokens":3}}}}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("COHERE_API_KEY", "test")
+	c, err := NewCohere(WithCohereBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	top := 0.9
+	req := Request{Model: "command-a-reasoning-08-2025", Prompt: "hi", Params: Params{TopP: &top, ThinkingBudget: 1024}}
+	var streamed string
+	resp, err := c.QueryStream(context.Background(), req, func(chunk string) { streamed += chunk })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !body.Stream || body.P == nil || *body.P != 0.9 {
+		t.Errorf("got stream %v, p %v", body.Stream, body.P)
+	}
+	if body.Thinking == nil || body.Thinking.Type != "enabled" || body.Thinking.TokenBudget != 1024 {
+		t.Errorf("got thinking %+v", body.Thinking)
+	}
+
+	if resp.Content != "Hello" || streamed != "Hello" || resp.Reasoning != "Adding." {
+		t.Errorf("got content %q, streamed %q, reasoning %q", resp.Content, streamed, resp.Reasoning)
+	}
+	// Usage counts the tokens processed, prompt template included
+	if resp.Usage != (Usage{InputTokens: 210, OutputTokens: 3}) || resp.FinishReason != "MAX_TOKENS" || resp.RequestID != "gen-1" {
+		t.Errorf("got usage %+v, finish reason %q, request ID %q", resp.Usage, resp.FinishReason, resp.RequestID)
+	}
+}
+
+func TestCohere_QueryStreamEnds(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  string
+		wantErr string // "" for success
+	}{
+		{
+			name: "message end",
+			events: `data: {"type":"content-delta","delta":{"message":{"content":{"text":"Hi"}}}}` + "\n\n" +
+				`data: {"type":"message-end","delta":{"finish_reason":"COMPLETE"}}` + "\n\n",
+		},
+		{
+			name:    "cut off",
+			events:  `data: {"type":"content-delta","delta":{"message":{"content":{"text":"Hi"}}}}` + "\n\n",
+			wantErr: "stream ended before the response was complete",
+		},
+		{
+			name: "error event",
+			events: `data: {"type":"content-delta","delta":{"message":{"content":{"text":"Hi"}}}}` + "\n\n" +
+				"event: error\n" + `data: {"message":"internal server error"}` + "\n\n",
+			wantErr: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.events)
+			}))
+			defer srv.Close()
+
+			t.Setenv("COHERE_API_KEY", "test")
+			c, err := NewCohere(WithCohereBaseURL(srv.URL))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			resp, err := c.QueryStream(context.Background(), Request{Model: "command-r", Prompt: "hi"}, nil)
+			if tt.wantErr != "" {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Message != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				if resp.Provider != "cohere" {
+					t.Errorf("got response %+v, want the partial result", resp)
+				}
+				return
+			}
+			if err != nil || resp.Content != "Hi" || resp.FinishReason != "COMPLETE" {
+				t.Errorf("got %q finishing %q, %v", resp.Content, resp.FinishReason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorClass groups API errors by cause, independent of vendor.
+type ErrorClass string
+
+const (
+	ErrorAuth           ErrorClass = "auth"            // bad or missing key, no access to the model
+	ErrorRateLimit      ErrorClass = "rate_limit"      // request or token quota exceeded
+	ErrorOverloaded     ErrorClass = "overloaded"      // vendor is shedding load
+	ErrorInvalidRequest ErrorClass = "invalid_request" // malformed request or unknown model
+	ErrorContextLength  ErrorClass = "context_length"  // prompt too long for the model
+	ErrorContentFilter  ErrorClass = "content_filter"  // blocked by a safety policy
+	ErrorServer         ErrorClass = "server"          // vendor-side failure
+	ErrorNetwork        ErrorClass = "network"         // no response received, or the stream broke off
+)
+
+// APIError is returned by providers when a request fails at the API,
+// either with an error status or an error event mid-stream, or when no
+// response arrives at all.
+type APIError struct {
+	StatusCode int           // HTTP status; 0 for stream and network errors
+	Type       string        // vendor error type, e.g. rate_limit_error or RESOURCE_EXHAUSTED
+	Code       string        // vendor error code, where one is sent
+	Message    string        // vendor message, or the raw body if it couldn't be parsed
+	RequestID  string        // vendor request ID, for support tickets
+	RetryAfter time.Duration // how long the vendor asked callers to wait; 0 if unset
+	Class      ErrorClass
+
+	Err error // underlying transport error for ErrorNetwork
+}
+
+func (e *APIError) Error() string {
+	if e.Class == ErrorNetwork {
+		return e.Message
+	}
+
+	var detail []string
+	if e.StatusCode != 0 {
+		detail = append(detail, "status "+strconv.Itoa(e.StatusCode))
+	}
+	if e.Type != "" {
+		detail = append(detail, e.Type)
+	}
+	if e.Code != "" && e.Code != e.Type {
+		detail = append(detail, e.Code)
+	}
+	if len(detail) == 0 {
+		return "API error: " + e.Message
+	}
+	return fmt.Sprintf("API error (%s): %s", strings.Join(detail, ", "), e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same request may succeed later.
+func (e *APIError) Retryable() bool {
+	switch e.Class {
+	case ErrorRateLimit, ErrorOverloaded, ErrorServer, ErrorNetwork:
+		return true
+	}
+	return false
+}
+
+// IsUserError reports whether err is an API error caused by the request
+// itself, malformed or blocked by a safety policy, which another model
+// would likely reject too. Unknown models and prompts too long for one
+// model's context window are not user errors.
+func IsUserError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Class {
+	case ErrorInvalidRequest:
+		return apiErr.StatusCode != http.StatusNotFound && apiErr.Type != "not_found_error"
+	case ErrorContentFilter:
+		return true
+	}
+	return false
+}
+
+// requestIDHeaders are the headers vendors return request IDs in.
+var requestIDHeaders = []string{"x-request-id", "request-id", "x-amzn-RequestId"}
+
+// newAPIError builds an APIError from an unsuccessful HTTP response and its
+// body. The common vendor error shapes are recognised:
+//
+//	OpenAI, Mistral:  {"error": {"message", "type", "code"}}
+//	Anthropic:        {"type": "error", "error": {"type", "message"}}
+//	Gemini:           {"error": {"code", "message", "status", "details"}}
+//	Cohere, Bedrock:  {"message"}
+//	Ollama:           {"error": "message"}
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	// Bedrock names the exception in a header, e.g.
+	// "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/"
+	if t := resp.Header.Get("x-amzn-ErrorType"); t != "" {
+		e.Type, _, _ = strings.Cut(t, ":")
+	}
+
+	parseErrorBody(e, body)
+	e.RetryAfter = max(e.RetryAfter, retryAfter(resp.Header))
+	e.Class = classify(e)
+	return e
+}
+
+// streamError builds an APIError from an error event received mid-stream.
+func streamError(typ, code, message string) *APIError {
+	e := &APIError{Type: typ, Code: code, Message: message}
+	e.Class = classify(e)
+	return e
+}
+
+// requestError wraps a failure to get any response. Cancellation and
+// deadlines are returned as they are so callers can still match them.
+func requestError(ctx context.Context, err error) error {
+	return networkError(ctx, "sending request", err)
+}
+
+// readError wraps a failure reading a stream after the response began,
+// such as a dropped connection, like requestError.
+func readError(ctx context.Context, err error) error {
+	return networkError(ctx, "reading stream", err)
+}
+
+func networkError(ctx context.Context, op string, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return &APIError{Message: op + ": " + err.Error(), Class: ErrorNetwork, Err: err}
+}
+
+// streamEnded returns the error for a stream that stopped before the event
+// marking a complete response, so a cut-off answer isn't taken as whole.
+// err is io.EOF when the server closed the stream early. Providers return
+// it with the response so far.
+func streamEnded(ctx context.Context, err error, requestID string) error {
+	if !errors.Is(err, io.EOF) {
+		return readError(ctx, err)
+	}
+	return &APIError{
+		Message:   "stream ended before the response was complete",
+		RequestID: requestID,
+		Class:     ErrorNetwork,
+	}
+}
+
+type vendorError struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+	Status  string          `json:"status"` // Gemini's canonical code name
+	Details []struct {
+		Type       string `json:"@type"`
+		RetryDelay string `json:"retryDelay"`
+	} `json:"details"`
+}
+
+// parseErrorBody fills e from the body's vendor error fields.
+func parseErrorBody(e *APIError, body []byte) {
+	var envelope struct {
+		vendorError
+		Error            json.RawMessage `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+		Detail           json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		e.Message = strings.TrimSpace(string(body))
+		return
+	}
+
+	v := envelope.vendorError
+	if v.Type == "error" {
+		v.Type = "" // Anthropic's envelope, not the error type
+	}
+	if len(envelope.Error) > 0 {
+		var msg string
+		if json.Unmarshal(envelope.Error, &msg) == nil {
+			v.Message = msg
+			// OAuth token endpoints send a code with a separate description
+			if envelope.ErrorDescription != "" {
+				v.Type, v.Message = msg, envelope.ErrorDescription
+			}
+		} else {
+			json.Unmarshal(envelope.Error, &v)
+		}
+	}
+
+	e.Message = v.Message
+	if e.Message == "" && len(envelope.Detail) > 0 {
+		e.Message = string(envelope.Detail)
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	if v.Type != "" {
+		e.Type = v.Type
+	} else if v.Status != "" {
+		e.Type = v.Status
+	}
+	if code := rawCode(v.Code); code != strconv.Itoa(e.StatusCode) {
+		e.Code = code
+	}
+	for _, d := range v.Details {
+		if strings.HasSuffix(d.Type, "google.rpc.RetryInfo") {
+			if wait, err := time.ParseDuration(d.RetryDelay); err == nil {
+				e.RetryAfter = wait
+			}
+		}
+	}
+}
+
+// rawCode converts an error code sent as either a string or a number.
+func rawCode(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// retryAfter reads Retry-After, as seconds or an HTTP date, preferring the
+// millisecond retry-after-ms header OpenAI and Azure send.
+func retryAfter(h http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(h.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// vendorClasses maps vendor error types and codes, lowercased, to classes.
+var vendorClasses = map[string]ErrorClass{
+	// Authentication and permissions
+	"authentication_error":        ErrorAuth,
+	"permission_error":            ErrorAuth,
+	"invalid_api_key":             ErrorAuth,
+	"unauthenticated":             ErrorAuth,
+	"permission_denied":           ErrorAuth,
+	"accessdeniedexception":       ErrorAuth,
+	"unrecognizedclientexception": ErrorAuth,
+	"insufficient_quota":          ErrorAuth, // billing, so waiting won't help
+	"invalid_grant":               ErrorAuth, // OAuth token exchange
+	"invalid_client":              ErrorAuth,
+
+	// Quotas
+	"rate_limit_error":    ErrorRateLimit,
+	"rate_limit_exceeded": ErrorRateLimit,
+	"resource_exhausted":  ErrorRateLimit,
+	"throttlingexception": ErrorRateLimit,
+
+	// Load shedding
+	"overloaded_error":            ErrorOverloaded,
+	"unavailable":                 ErrorOverloaded,
+	"serviceunavailableexception": ErrorOverloaded,
+	"modelnotreadyexception":      ErrorOverloaded,
+
+	// Vendor-side failures
+	"api_error":                 ErrorServer,
+	"server_error":              ErrorServer,
+	"internal":                  ErrorServer,
+	"internalserverexception":   ErrorServer,
+	"modelstreamerrorexception": ErrorServer,
+	"modeltimeoutexception":     ErrorServer,
+
+	// Content policy
+	"content_filter":               ErrorContentFilter,
+	"content_policy_violation":     ErrorContentFilter,
+	"responsibleaipolicyviolation": ErrorContentFilter,
+
+	// Malformed requests
+	"invalid_request_error": ErrorInvalidRequest,
+	"invalid_argument":      ErrorInvalidRequest,
+	"not_found_error":       ErrorInvalidRequest,
+	"validationexception":   ErrorInvalidRequest,
+
+	// Context window
+	"context_length_exceeded": ErrorContextLength,
+	"string_above_max_length": ErrorContextLength,
+}
+
+// contextLengthMessages and contentFilterMessages identify the cause of
+// invalid-request errors whose type and code are generic.
+var (
+	contextLengthMessages = []string{
+		"context length", "context window", "maximum context",
+		"prompt is too long", "input is too long", "too many tokens",
+		"exceeds the maximum number of tokens",
+	}
+	contentFilterMessages = []string{
+		"content management policy", "content policy", "content filter",
+	}
+)
+
+// classify determines the class of e from its vendor type and code,
+// falling back to the HTTP status.
+func classify(e *APIError) ErrorClass {
+	class, ok := vendorClasses[strings.ToLower(e.Code)]
+	if !ok {
+		class, ok = vendorClasses[strings.ToLower(e.Type)]
+	}
+	if !ok {
+		switch s := e.StatusCode; {
+		case s == http.StatusUnauthorized || s == http.StatusForbidden:
+			class = ErrorAuth
+		case s == http.StatusTooManyRequests:
+			class = ErrorRateLimit
+		case s == http.StatusServiceUnavailable || s == 529: // 529: Anthropic overloaded
+			class = ErrorOverloaded
+		case s == http.StatusRequestTimeout || s >= 500 || s == 0:
+			class = ErrorServer
+		default:
+			class = ErrorInvalidRequest
+		}
+	}
+	if class != ErrorInvalidRequest {
+		return class
+	}
+
+	msg := strings.ToLower(e.Message)
+	for _, m := range contextLengthMessages {
+		if strings.Contains(msg, m) {
+			return ErrorContextLength
+		}
+	}
+	for _, m := range contentFilterMessages {
+		if strings.Contains(msg, m) {
+			return ErrorContentFilter
+		}
+	}
+	return ErrorInvalidRequest
+}
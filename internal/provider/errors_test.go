@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    http.Header
+		body      string
+		wantClass ErrorClass
+		wantType  string
+		wantCode  string
+		wantMsg   string
+		wantID    string
+		wantRetry time.Duration
+		retryable bool
+	}{
+		{
+			name:      "openai rate limit",
+			status:    429,
+			header:    http.Header{"X-Request-Id": {"req_1"}, "Retry-After-Ms": {"1500"}},
+			body:      `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantClass: ErrorRateLimit, wantType: "requests", wantCode: "rate_limit_exceeded", wantMsg: "Rate limit reached",
+			wantID: "req_1", wantRetry: 1500 * time.Millisecond, retryable: true,
+		},
+		{
+			name:      "openai context length",
+			status:    400,
+			body:      `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			wantClass: ErrorContextLength, wantType: "invalid_request_error", wantCode: "context_length_exceeded",
+		},
+		{
+			name:      "openai quota",
+			status:    429,
+			body:      `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantClass: ErrorAuth, wantType: "insufficient_quota", wantCode: "insufficient_quota",
+		},
+		{
+			name:      "azure content filter",
+			status:    400,
+			body:      `{"error":{"message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy.","code":"content_filter"}}`,
+			wantClass: ErrorContentFilter, wantCode: "content_filter",
+		},
+		{
+			name:      "anthropic overloaded",
+			status:    529,
+			header:    http.Header{"Request-Id": {"req_2"}, "Retry-After": {"3"}},
+			body:      `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			wantClass: ErrorOverloaded, wantType: "overloaded_error", wantMsg: "Overloaded",
+			wantID: "req_2", wantRetry: 3 * time.Second, retryable: true,
+		},
+		{
+			name:      "anthropic prompt too long",
+			status:    400,
+			body:      `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			wantClass: ErrorContextLength, wantType: "invalid_request_error",
+		},
+		{
+			name:      "anthropic auth",
+			status:    401,
+			body:      `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			wantClass: ErrorAuth, wantType: "authentication_error",
+		},
+		{
+			name:   "gemini quota with retry info",
+			status: 429,
+			body: `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED",` +
+				`"details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"27s"}]}}`,
+			wantClass: ErrorRateLimit, wantType: "RESOURCE_EXHAUSTED", wantRetry: 27 * time.Second, retryable: true,
+		},
+		{
+			name:      "gemini context length",
+			status:    400,
+			body:      `{"error":{"code":400,"message":"The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`,
+			wantClass: ErrorContextLength, wantType: "INVALID_ARGUMENT",
+		},
+		{
+			name:      "bedrock throttling",
+			status:    429,
+			header:    http.Header{"X-Amzn-Errortype": {"ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/"}, "X-Amzn-Requestid": {"req_3"}},
+			body:      `{"message":"Too many requests, please wait before trying again."}`,
+			wantClass: ErrorRateLimit, wantType: "ThrottlingException", wantID: "req_3", retryable: true,
+		},
+		{
+			name:      "ollama",
+			status:    404,
+			body:      `{"error":"model \"llama9\" not found"}`,
+			wantClass: ErrorInvalidRequest, wantMsg: `model "llama9" not found`,
+		},
+		{
+			name:      "plain text gateway error",
+			status:    502,
+			body:      "Bad Gateway\n",
+			wantClass: ErrorServer, wantMsg: "Bad Gateway", retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			e := newAPIError(&http.Response{StatusCode: tt.status, Header: header}, []byte(tt.body))
+
+			if e.Class != tt.wantClass {
+				t.Errorf("got class %q, want %q", e.Class, tt.wantClass)
+			}
+			if e.Type != tt.wantType || e.Code != tt.wantCode {
+				t.Errorf("got type %q code %q, want %q %q", e.Type, e.Code, tt.wantType, tt.wantCode)
+			}
+			if tt.wantMsg != "" && e.Message != tt.wantMsg {
+				t.Errorf("got message %q, want %q", e.Message, tt.wantMsg)
+			}
+			if e.RequestID != tt.wantID {
+				t.Errorf("got request ID %q, want %q", e.RequestID, tt.wantID)
+			}
+			if e.RetryAfter != tt.wantRetry {
+				t.Errorf("got retry after %v, want %v", e.RetryAfter, tt.wantRetry)
+			}
+			if e.Retryable() != tt.retryable {
+				t.Errorf("got retryable %v, want %v", e.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestAnthropic_QueryStreamErrorEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("request-id", "req_123")
+		fmt.Fprint(w, "event: message_start\n"+
+			`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`+"\n\n"+
+			"event: error\n"+
+			`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	a, err := NewAnthropic(WithAnthropicBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = a.QueryStream(context.Background(), Request{Model: "claude-sonnet-4-5", Prompt: "hi"}, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got error %v, want *APIError", err)
+	}
+	if apiErr.Class != ErrorOverloaded || apiErr.RequestID != "req_123" || !apiErr.Retryable() {
+		t.Errorf("got %+v", apiErr)
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	err := requestError(context.Background(), errors.New("connection refused"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Class != ErrorNetwork {
+		t.Errorf("got %v, want a network APIError", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := requestError(ctx, context.Canceled); errors.As(err, &apiErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want cancellation passed through", err)
+	}
+}
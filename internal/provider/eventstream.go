@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// AWS event stream binary framing, used by Bedrock's streaming APIs.
+// Spec: https://docs.aws.amazon.com/transcribe/latest/dg/streaming-setting-up.html#streaming-event-stream
+//
+// Each message is:
+//
+//	total length (4) | headers length (4) | prelude CRC (4) | headers | payload | message CRC (4)
+//
+// All integers are big-endian and both CRCs are CRC-32 (IEEE).
+
+const (
+	eventStreamPreludeLen = 12
+	eventStreamMaxMessage = 16 << 20
+)
+
+// eventStreamMessage is one decoded event stream frame.
+type eventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// eventStreamReader decodes consecutive messages from an event stream.
+type eventStreamReader struct {
+	r io.Reader
+}
+
+func newEventStreamReader(r io.Reader) *eventStreamReader {
+	return &eventStreamReader{r: r}
+}
+
+// Next reads the next message. It returns io.EOF at a clean end of stream.
+func (e *eventStreamReader) Next() (eventStreamMessage, error) {
+	prelude := make([]byte, eventStreamPreludeLen)
+	if _, err := io.ReadFull(e.r, prelude); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return eventStreamMessage{}, fmt.Errorf("event stream: truncated prelude")
+		}
+		return eventStreamMessage{}, err
+	}
+
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return eventStreamMessage{}, errors.New("event stream: prelude checksum mismatch")
+	}
+	if totalLen < eventStreamPreludeLen+4+headersLen || totalLen > eventStreamMaxMessage {
+		return eventStreamMessage{}, fmt.Errorf("event stream: invalid message length %d", totalLen)
+	}
+
+	rest := make([]byte, totalLen-eventStreamPreludeLen)
+	if _, err := io.ReadFull(e.r, rest); err != nil {
+		return eventStreamMessage{}, fmt.Errorf("event stream: truncated message: %w", err)
+	}
+
+	body := rest[:len(rest)-4]
+	crc := crc32.NewIEEE()
+	crc.Write(prelude)
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-4:]) {
+		return eventStreamMessage{}, errors.New("event stream: message checksum mismatch")
+	}
+
+	headers, err := decodeEventStreamHeaders(body[:headersLen])
+	if err != nil {
+		return eventStreamMessage{}, err
+	}
+
+	return eventStreamMessage{
+		Headers: headers,
+		Payload: body[headersLen:],
+	}, nil
+}
+
+// decodeEventStreamHeaders parses the header block. Only string-valued
+// headers are kept; other types are skipped since Bedrock doesn't use them.
+func decodeEventStreamHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errors.New("event stream: truncated header")
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // bool true / false
+			size = 0
+		case 2: // byte
+			size = 1
+		case 3: // int16
+			size = 2
+		case 4: // int32
+			size = 4
+		case 5, 8: // int64, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // bytes, string
+			if len(b) < 2 {
+				return nil, errors.New("event stream: truncated header")
+			}
+			size = int(binary.BigEndian.Uint16(b[0:2]))
+			b = b[2:]
+		default:
+			return nil, fmt.Errorf("event stream: unknown header type %d", valueType)
+		}
+
+		if len(b) < size {
+			return nil, errors.New("event stream: truncated header value")
+		}
+		if valueType == 7 {
+			headers[name] = string(b[:size])
+		}
+		b = b[size:]
+	}
+	return headers, nil
+}
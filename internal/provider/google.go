@@ -1,9 +1,9 @@
 package provider
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,11 +30,18 @@ import (
 //   - gemini-2.0-flash           : Second generation workhorse, 1M context
 //   - gemini-2.0-flash-lite      : Second generation small workhorse, 1M context
 
-// Google implements Provider for Google's Gemini API.
+// Google implements Provider for Google's Gemini API, either through the
+// Gemini Developer API (API key) or Vertex AI (service account, see vertex.go).
 type Google struct {
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+
+	// Vertex AI mode; tokens is nil for the Developer API.
+	project  string
+	location string
+	credFile string
+	tokens   *serviceAccountTokenSource
 }
 
 // GoogleOption configures a Google provider.
@@ -61,7 +68,7 @@ func NewGoogle(opts ...GoogleOption) (*Google, error) {
 	g := &Google{
 		apiKey:     apiKey,
 		baseURL:    "https://generativelanguage.googleapis.com/v1beta",
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		httpClient: defaultHTTPClient,
 	}
 
 	for _, opt := range opts {
@@ -75,34 +82,21 @@ func NewGoogle(opts ...GoogleOption) (*Google, error) {
 func (g *Google) Query(ctx context.Context, req Request) (Response, error) {
 	start := time.Now()
 
-	payload := geminiRequest{
-		Contents: []geminiContent{
-			{
-				Parts: []geminiPart{
-					{Text: req.Prompt},
-				},
-			},
-		},
-	}
+	payload := buildGeminiRequest(req)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return Response{}, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	// Gemini uses model name in URL path
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", g.baseURL, req.Model, g.apiKey)
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	httpReq, err := g.newRequest(ctx, req.Model, "generateContent", body)
 	if err != nil {
-		return Response{}, fmt.Errorf("creating request: %w", err)
+		return Response{}, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	resp, err := g.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
@@ -112,7 +106,7 @@ func (g *Google) Query(ctx context.Context, req Request) (Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
 	var geminiResp geminiResponse
@@ -120,31 +114,29 @@ func (g *Google) Query(ctx context.Context, req Request) (Response, error) {
 		return Response{}, fmt.Errorf("parsing response: %w", err)
 	}
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+	content, thoughts := geminiResp.text()
+	calls := geminiResp.calls(nil)
+	if content == "" && len(calls) == 0 {
 		return Response{}, errors.New("no content in response")
 	}
 
-	return Response{
-		Model:    req.Model,
-		Content:  geminiResp.Candidates[0].Content.Parts[0].Text,
-		Provider: "google",
-		Latency:  time.Since(start),
-	}, nil
+	result := Response{
+		Model:     req.Model,
+		Content:   content,
+		Reasoning: thoughts,
+		Provider:  g.name(),
+		ToolCalls: calls,
+	}
+	geminiResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
 }
 
 // QueryStream sends a prompt to a Gemini model and streams the response.
 func (g *Google) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
 	start := time.Now()
 
-	payload := geminiRequest{
-		Contents: []geminiContent{
-			{
-				Parts: []geminiPart{
-					{Text: req.Prompt},
-				},
-			},
-		},
-	}
+	payload := buildGeminiRequest(req)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -152,79 +144,386 @@ func (g *Google) QueryStream(ctx context.Context, req Request, callback StreamCa
 	}
 
 	// Gemini uses streamGenerateContent endpoint for streaming
-	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s&alt=sse", g.baseURL, req.Model, g.apiKey)
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	httpReq, err := g.newRequest(ctx, req.Model, "streamGenerateContent", body)
 	if err != nil {
-		return Response{}, fmt.Errorf("creating request: %w", err)
+		return Response{}, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	resp, err := g.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
-	var fullContent strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+	result := Response{
+		Model:    req.Model,
+		Provider: g.name(),
+	}
+
+	var fullContent, reasoning strings.Builder
+	events := newSSEReader(resp.Body)
+	for {
+		ev, err := events.Next()
+		if errors.Is(err, io.EOF) && result.FinishReason != "" {
+			break // the last chunk carries the finish reason
+		}
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
 		}
-		data := strings.TrimPrefix(line, "data: ")
 
 		var streamResp geminiResponse
-		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
+		if err := json.Unmarshal([]byte(ev.Data), &streamResp); err != nil {
 			continue
 		}
 
-		if len(streamResp.Candidates) > 0 && len(streamResp.Candidates[0].Content.Parts) > 0 {
-			chunk := streamResp.Candidates[0].Content.Parts[0].Text
+		chunk, thoughts := streamResp.text()
+		reasoning.WriteString(thoughts)
+		result.ToolCalls = streamResp.calls(result.ToolCalls)
+		if chunk != "" {
 			fullContent.WriteString(chunk)
 			if callback != nil {
 				callback(chunk)
 			}
 		}
+
+		streamResp.fill(&result)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return Response{}, fmt.Errorf("reading stream: %w", err)
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// newRequest builds an authenticated POST to a model method such as
+// generateContent. The model name goes in the URL path.
+func (g *Google) newRequest(ctx context.Context, model, method string, body []byte) (*http.Request, error) {
+	model = strings.TrimPrefix(model, VertexPrefix)
+
+	var url string
+	if g.tokens != nil {
+		url = fmt.Sprintf("%s/projects/%s/locations/%s/publishers/google/models/%s:%s",
+			g.baseURL, g.project, g.location, model, method)
+	} else {
+		url = fmt.Sprintf("%s/models/%s:%s?key=%s", g.baseURL, model, method, g.apiKey)
+	}
+	if method == "streamGenerateContent" {
+		if g.tokens != nil {
+			url += "?alt=sse"
+		} else {
+			url += "&alt=sse"
+		}
 	}
 
-	return Response{
-		Model:    req.Model,
-		Content:  fullContent.String(),
-		Provider: "google",
-		Latency:  time.Since(start),
-	}, nil
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	if g.tokens != nil {
+		token, err := g.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching access token: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return httpReq, nil
+}
+
+// SupportsAttachment reports whether Gemini accepts the MIME type as
+// inline data. Gemini takes PNG, JPEG, WebP and HEIC/HEIF images, not GIF.
+func (g *Google) SupportsAttachment(model, mimeType string) bool {
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/webp", "image/heic", "image/heif", "application/pdf":
+		return true
+	}
+	return false
+}
+
+// SupportsTools reports that Gemini accepts function declarations.
+func (g *Google) SupportsTools(model string) bool {
+	return true
+}
+
+// name reports which Google API served the request.
+func (g *Google) name() string {
+	if g.tokens != nil {
+		return "vertex"
+	}
+	return "google"
+}
+
+// buildGeminiRequest maps a Request onto the generateContent payload.
+// Gemini calls the assistant role "model", and system turns move to
+// systemInstruction. A schema is passed as responseJsonSchema, which takes
+// full JSON Schema ($ref and $defs included) where responseSchema only takes
+// an OpenAPI subset; Gemini only honors it with the JSON response MIME
+// type. Tool calls go back as
+// functionCall parts with their thought signatures, and results as
+// functionResponse parts in a user turn. NoToolCalls sets the calling
+// mode to NONE.
+func buildGeminiRequest(req Request) geminiRequest {
+	system, turns := req.splitSystem()
+
+	var payload geminiRequest
+	for _, m := range turns {
+		role := m.Role
+		if role == RoleAssistant {
+			role = "model"
+		}
+		// Attachments go in their turn as inline data ahead of the text
+		var parts []geminiPart
+		for _, a := range m.Attachments {
+			parts = append(parts, geminiPart{InlineData: &geminiBlob{
+				MimeType: a.MIMEType,
+				Data:     base64.StdEncoding.EncodeToString(a.Data),
+			}})
+		}
+		payload.Contents = append(payload.Contents, geminiContent{
+			Role:  role,
+			Parts: append(parts, geminiPart{Text: m.Content}),
+		})
+	}
+
+	for _, m := range req.ToolTurns {
+		content := geminiContent{Role: "model"}
+		if m.Role == RoleTool {
+			content.Role = RoleUser
+		}
+		if m.Content != "" {
+			content.Parts = append(content.Parts, geminiPart{Text: m.Content})
+		}
+		for _, c := range m.ToolCalls {
+			content.Parts = append(content.Parts, geminiPart{
+				FunctionCall:     &geminiFunctionCall{Name: c.Name, Args: c.toolArguments()},
+				ThoughtSignature: c.Signature,
+			})
+		}
+		for _, r := range m.ToolResults {
+			// The response must be an object
+			key := "content"
+			if r.IsError {
+				key = "error"
+			}
+			result, _ := json.Marshal(map[string]string{key: r.Content})
+			content.Parts = append(content.Parts, geminiPart{
+				FunctionResponse: &geminiFunctionResponse{Name: r.Name, Response: result},
+			})
+		}
+		payload.Contents = append(payload.Contents, content)
+	}
+
+	if len(req.Tools) > 0 {
+		var decls []geminiFunctionDeclaration
+		for _, t := range req.Tools {
+			decls = append(decls, geminiFunctionDeclaration{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+		}
+		payload.Tools = []geminiTool{{FunctionDeclarations: decls}}
+		if req.NoToolCalls {
+			payload.ToolConfig = &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "NONE"}}
+		}
+	}
+
+	if system != "" {
+		payload.SystemInstruction = &geminiContent{
+			Parts: []geminiPart{{Text: system}},
+		}
+	}
+
+	if req.Temperature != nil || req.TopP != nil || req.MaxTokens != 0 || len(req.Stop) > 0 || req.Seed != nil ||
+		req.ReasoningEffort != "" || req.thinkingBudget() > 0 || req.Schema != nil {
+		payload.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: req.MaxTokens,
+			StopSequences:   req.Stop,
+			Seed:            req.Seed,
+			ThinkingConfig:  geminiThinking(req.Params),
+		}
+		if req.Schema != nil {
+			payload.GenerationConfig.ResponseMimeType = "application/json"
+			payload.GenerationConfig.ResponseJsonSchema = req.Schema
+		}
+	}
+
+	return payload
+}
+
+// geminiThinking maps reasoning settings onto thinkingConfig. Thought
+// summaries are only returned when includeThoughts is set; a zero budget
+// turns thinking off on models that allow it.
+func geminiThinking(p Params) *geminiThinkingConfig {
+	if p.ReasoningEffort == "none" {
+		budget := 0
+		return &geminiThinkingConfig{ThinkingBudget: &budget}
+	}
+	if !p.reasoning() {
+		return nil
+	}
+	cfg := &geminiThinkingConfig{IncludeThoughts: true}
+	if budget := p.thinkingBudget(); budget > 0 {
+		cfg.ThinkingBudget = &budget
+	}
+	return cfg
 }
 
 type geminiRequest struct {
-	Contents []geminiContent `json:"contents"`
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode string `json:"mode"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature        *float64              `json:"temperature,omitempty"`
+	TopP               *float64              `json:"topP,omitempty"`
+	MaxOutputTokens    int                   `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string              `json:"stopSequences,omitempty"`
+	Seed               *int64                `json:"seed,omitempty"`
+	ThinkingConfig     *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+	ResponseMimeType   string                `json:"responseMimeType,omitempty"`
+	ResponseJsonSchema json.RawMessage       `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
 }
 
 type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
 type geminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
 }
 
 type geminiResponse struct {
 	Candidates []struct {
 		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
+			Parts []geminiPart `json:"parts"`
 		} `json:"content"`
+		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+	ResponseID   string `json:"responseId"`
+}
+
+// text returns the answer and thought-summary text of the first candidate.
+func (r *geminiResponse) text() (content, thoughts string) {
+	if len(r.Candidates) == 0 {
+		return "", ""
+	}
+	var c, t strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		if part.Thought {
+			t.WriteString(part.Text)
+		} else {
+			c.WriteString(part.Text)
+		}
+	}
+	return c.String(), t.String()
+}
+
+// calls appends the function calls of the first candidate to calls. Calls
+// without an ID are numbered, as older models don't send one.
+func (r *geminiResponse) calls(calls []ToolCall) []ToolCall {
+	if len(r.Candidates) == 0 {
+		return calls
+	}
+	for _, part := range r.Candidates[0].Content.Parts {
+		fc := part.FunctionCall
+		if fc == nil {
+			continue
+		}
+		id := fc.ID
+		if id == "" {
+			id = fmt.Sprintf("%s-%d", fc.Name, len(calls))
+		}
+		calls = append(calls, ToolCall{ID: id, Name: fc.Name, Arguments: fc.Args, Signature: part.ThoughtSignature})
+	}
+	return calls
+}
+
+// fill copies the metadata present in r onto resp. Stream chunks each carry
+// cumulative usage, and only the last one has a finish reason, so fields are
+// overwritten only when set.
+func (r *geminiResponse) fill(resp *Response) {
+	if r.UsageMetadata != nil {
+		// candidatesTokenCount excludes thinking tokens
+		resp.Usage = Usage{
+			InputTokens:     r.UsageMetadata.PromptTokenCount,
+			OutputTokens:    r.UsageMetadata.CandidatesTokenCount + r.UsageMetadata.ThoughtsTokenCount,
+			ReasoningTokens: r.UsageMetadata.ThoughtsTokenCount,
+			CachedTokens:    r.UsageMetadata.CachedContentTokenCount,
+		}
+	}
+	if len(r.Candidates) > 0 && r.Candidates[0].FinishReason != "" {
+		resp.FinishReason = r.Candidates[0].FinishReason
+	}
+	if r.ModelVersion != "" {
+		resp.ResolvedModel = r.ModelVersion
+	}
+	if r.ResponseID != "" {
+		resp.RequestID = r.ResponseID
+	}
 }
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Middleware decorates a Provider, for example to log, time, limit or
+// cache its queries. Decorated providers keep the attachment and tool
+// support of the provider they wrap unless they declare their own.
+type Middleware func(Provider) Provider
+
+// Chain composes middlewares into one. The first is the outermost: it sees
+// each query first and its response last.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(p Provider) Provider {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			p = decorate(middlewares[i], p)
+		}
+		return p
+	}
+}
+
+// decorate applies m to p, keeping p's capabilities visible.
+func decorate(m Middleware, p Provider) Provider {
+	if m == nil {
+		return p
+	}
+	return decorated{Provider: m(p), inner: p}
+}
+
+// decorated forwards the optional capability interfaces to the wrapped
+// provider when the decorator doesn't implement them itself.
+type decorated struct {
+	Provider
+	inner Provider
+}
+
+func (d decorated) SupportsAttachment(model, mimeType string) bool {
+	if s, ok := d.Provider.(AttachmentSupporter); ok {
+		return s.SupportsAttachment(model, mimeType)
+	}
+	return Accepts(d.inner, model, mimeType)
+}
+
+func (d decorated) SupportsTools(model string) bool {
+	if tc, ok := d.Provider.(ToolCaller); ok {
+		return tc.SupportsTools(model)
+	}
+	return CallsTools(d.inner, model)
+}
+
+// streamFunc adapts a function to a Provider whose Query streams with no
+// callback, so middlewares only need to write the streaming path.
+type streamFunc func(ctx context.Context, req Request, callback StreamCallback) (Response, error)
+
+func (f streamFunc) Query(ctx context.Context, req Request) (Response, error) {
+	return f(ctx, req, nil)
+}
+
+func (f streamFunc) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	return f(ctx, req, callback)
+}
+
+// Logging logs every query to logger when it finishes: at info level with
+// latency, usage and finish reason, or at warn level with the error.
+func Logging(logger *slog.Logger) Middleware {
+	return func(next Provider) Provider {
+		return streamFunc(func(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+			start := time.Now()
+			resp, err := next.QueryStream(ctx, req, callback)
+
+			attrs := []slog.Attr{
+				slog.String("model", req.Model),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				var apiErr *APIError
+				if errors.As(err, &apiErr) {
+					attrs = append(attrs, slog.String("class", string(apiErr.Class)))
+				}
+				logger.LogAttrs(ctx, slog.LevelWarn, "query failed", attrs...)
+				return resp, err
+			}
+			attrs = append(attrs,
+				slog.String("provider", resp.Provider),
+				slog.Int("input_tokens", resp.Usage.InputTokens),
+				slog.Int("output_tokens", resp.Usage.OutputTokens),
+				slog.String("finish_reason", resp.FinishReason),
+				slog.String("request_id", resp.RequestID),
+			)
+			logger.LogAttrs(ctx, slog.LevelInfo, "query", attrs...)
+			return resp, nil
+		})
+	}
+}
+
+// Timing is one query's timing as measured by the Timing middleware.
+type Timing struct {
+	Model      string
+	FirstChunk time.Duration // until the first streamed chunk; 0 if none arrived
+	Total      time.Duration
+	Err        error
+}
+
+// Timed calls report with the timing of every query when it finishes.
+// report may be called concurrently.
+func Timed(report func(Timing)) Middleware {
+	return func(next Provider) Provider {
+		return streamFunc(func(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+			start := time.Now()
+			t := Timing{Model: req.Model}
+			cb := func(chunk string) {
+				if t.FirstChunk == 0 {
+					t.FirstChunk = time.Since(start)
+				}
+				if callback != nil {
+					callback(chunk)
+				}
+			}
+
+			resp, err := next.QueryStream(ctx, req, cb)
+			t.Total = time.Since(start)
+			t.Err = err
+			report(t)
+			return resp, err
+		})
+	}
+}
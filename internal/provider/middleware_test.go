@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tag records the order in which middlewares see a query.
+func tag(name string, seen *[]string) Middleware {
+	return func(next Provider) Provider {
+		return ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+			*seen = append(*seen, name)
+			return next.Query(ctx, req)
+		})
+	}
+}
+
+func TestRegistry_Middleware(t *testing.T) {
+	var seen []string
+	reg := NewRegistry()
+	reg.Use(tag("global", &seen))
+	reg.Register("m", toolCapable{func(ctx context.Context, req Request) (Response, error) {
+		seen = append(seen, "provider")
+		return Response{Content: "ok"}, nil
+	}}, tag("outer", &seen), nil, tag("inner", &seen))
+
+	p, err := reg.Get("m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.Query(context.Background(), Request{Model: "m"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"global", "outer", "inner", "provider"}
+	if !slices.Equal(seen, want) {
+		t.Errorf("got order %v, want %v", seen, want)
+	}
+	if !CallsTools(p, "m") {
+		t.Error("middlewares hide tool support")
+	}
+	if Accepts(p, "m", "image/png") {
+		t.Error("middlewares invent attachment support")
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	ok := Chain(Logging(logger))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+		return Response{Provider: "test", Content: "hi", Usage: Usage{InputTokens: 3, OutputTokens: 2}, RequestID: "req_1"}, nil
+	}))
+	failing := Chain(Logging(logger))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+		return Response{}, &APIError{StatusCode: 429, Class: ErrorRateLimit, Message: "slow down"}
+	}))
+
+	ok.Query(context.Background(), Request{Model: "a"})
+	failing.QueryStream(context.Background(), Request{Model: "b"}, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, want := range []string{"level=INFO", "model=a", "input_tokens=3", "request_id=req_1"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("success line missing %q: %s", want, lines[0])
+		}
+	}
+	for _, want := range []string{"level=WARN", "model=b", "class=rate_limit"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("failure line missing %q: %s", want, lines[1])
+		}
+	}
+}
+
+func TestTimed(t *testing.T) {
+	var got []Timing
+	p := Chain(Timed(func(t Timing) { got = append(got, t) }))(ProviderFunc(func(ctx context.Context, req Request) (Response, error) {
+		if req.Model == "bad" {
+			return Response{}, errors.New("boom")
+		}
+		return Response{Content: "hi"}, nil
+	}))
+
+	var chunks []string
+	p.QueryStream(context.Background(), Request{Model: "good"}, func(c string) { chunks = append(chunks, c) })
+	p.Query(context.Background(), Request{Model: "bad"})
+
+	if len(got) != 2 {
+		t.Fatalf("got %d timings, want 2", len(got))
+	}
+	if got[0].Model != "good" || got[0].FirstChunk == 0 || got[0].Total < got[0].FirstChunk || got[0].Err != nil {
+		t.Errorf("got %+v", got[0])
+	}
+	if len(chunks) != 1 || chunks[0] != "hi" {
+		t.Errorf("callback not passed through: %v", chunks)
+	}
+	if got[1].Model != "bad" || got[1].Err == nil {
+		t.Errorf("got %+v", got[1])
+	}
+}
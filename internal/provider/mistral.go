@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mistral Models
+// Full list: https://docs.mistral.ai/getting-started/models/models_overview/
+//
+// Premier:
+//   - mistral-large-latest     : Top-tier reasoning for complex tasks
+//   - mistral-medium-latest    : Frontier-class multimodal model
+//   - magistral-medium-latest  : Reasoning model
+//   - codestral-latest         : Coding model
+//
+// Small:
+//   - mistral-small-latest     : Efficient model for low-latency tasks
+//   - ministral-8b-latest      : Edge model
+
+// Mistral implements Provider for Mistral's chat completions API.
+type Mistral struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// MistralOption configures a Mistral provider.
+type MistralOption func(*Mistral)
+
+// WithMistralBaseURL sets a custom base URL.
+func WithMistralBaseURL(url string) MistralOption {
+	return func(m *Mistral) { m.baseURL = url }
+}
+
+// WithMistralHTTPClient sets a custom HTTP client.
+func WithMistralHTTPClient(c *http.Client) MistralOption {
+	return func(m *Mistral) { m.httpClient = c }
+}
+
+// NewMistral creates a Mistral provider.
+// Reads API key from MISTRAL_API_KEY environment variable.
+func NewMistral(opts ...MistralOption) (*Mistral, error) {
+	apiKey := os.Getenv("MISTRAL_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("MISTRAL_API_KEY environment variable required")
+	}
+
+	m := &Mistral{
+		apiKey:     apiKey,
+		baseURL:    "https://api.mistral.ai/v1",
+		httpClient: defaultHTTPClient,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+// Query sends a prompt to a Mistral model and returns the response.
+func (m *Mistral) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildMistralRequest(req, false)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var mistralResp mistralResponse
+	if err := json.Unmarshal(respBody, &mistralResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if len(mistralResp.Choices) == 0 || mistralResp.Choices[0].Message.Content.Text == "" {
+		return Response{}, errors.New("no content in response")
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Content:   mistralResp.Choices[0].Message.Content.Text,
+		Reasoning: mistralResp.Choices[0].Message.Content.Thinking,
+		Provider:  "mistral",
+	}
+	mistralResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// QueryStream sends a prompt to a Mistral model and streams the response.
+func (m *Mistral) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildMistralRequest(req, true)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:    req.Model,
+		Provider: "mistral",
+	}
+
+	var fullContent, reasoning strings.Builder
+	events := newSSEReader(resp.Body)
+	for {
+		ev, err := events.Next()
+		if errors.Is(err, io.EOF) && result.FinishReason != "" {
+			break // not every server sends [DONE]
+		}
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		if ev.Data == "[DONE]" {
+			break
+		}
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		}
+
+		var chunk mistralResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			continue
+		}
+
+		if len(chunk.Choices) > 0 {
+			reasoning.WriteString(chunk.Choices[0].Delta.Content.Thinking)
+		}
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content.Text != "" {
+			text := chunk.Choices[0].Delta.Content.Text
+			fullContent.WriteString(text)
+			if callback != nil {
+				callback(text)
+			}
+		}
+
+		// The last chunk carries usage alongside the finish reason
+		chunk.fill(&result)
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// Mistral chat completions API types
+// https://docs.mistral.ai/api/#tag/chat
+
+// buildMistralRequest maps a Request onto the chat completions payload.
+// Mistral calls the seed "random_seed".
+func buildMistralRequest(req Request, stream bool) mistralRequest {
+	var messages []mistralMessage
+	if req.System != "" {
+		messages = append(messages, mistralMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Conversation() {
+		messages = append(messages, mistralMessage{Role: m.Role, Content: m.Content})
+	}
+
+	return mistralRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		MaxTokens:      req.MaxTokens,
+		Stop:           req.Stop,
+		RandomSeed:     req.Seed,
+		ResponseFormat: chatSchemaFormat(req.Schema),
+		Stream:         stream,
+	}
+}
+
+type mistralRequest struct {
+	Model          string              `json:"model"`
+	Messages       []mistralMessage    `json:"messages"`
+	Temperature    *float64            `json:"temperature,omitempty"`
+	TopP           *float64            `json:"top_p,omitempty"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	Stop           []string            `json:"stop,omitempty"`
+	RandomSeed     *int64              `json:"random_seed,omitempty"`
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                `json:"stream,omitempty"`
+}
+
+type mistralMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type mistralResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content mistralContent `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content mistralContent `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatCompletionsUsage `json:"usage"`
+}
+
+// mistralContent is a response message's content. Most models send a plain
+// string; reasoning models (Magistral) send an array of chunks in which
+// "thinking" chunks carry the reasoning trace.
+type mistralContent struct {
+	Text     string
+	Thinking string
+}
+
+func (c *mistralContent) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		c.Text = s
+		return nil
+	}
+
+	var chunks []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking []struct {
+			Text string `json:"text"`
+		} `json:"thinking"`
+	}
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return err
+	}
+
+	var text, thinking strings.Builder
+	for _, chunk := range chunks {
+		switch chunk.Type {
+		case "text":
+			text.WriteString(chunk.Text)
+		case "thinking":
+			for _, t := range chunk.Thinking {
+				thinking.WriteString(t.Text)
+			}
+		}
+	}
+	c.Text, c.Thinking = text.String(), thinking.String()
+	return nil
+}
+
+// fill copies the metadata present in r onto resp, overwriting only fields
+// that are set so stream chunks can be applied in turn.
+func (r *mistralResponse) fill(resp *Response) {
+	if r.Usage != nil {
+		resp.Usage = r.Usage.usage()
+	}
+	if len(r.Choices) > 0 && r.Choices[0].FinishReason != "" {
+		resp.FinishReason = r.Choices[0].FinishReason
+	}
+	if r.Model != "" {
+		resp.ResolvedModel = r.Model
+	}
+	if r.ID != "" {
+		resp.RequestID = r.ID
+	}
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMistral_QueryStream(t *testing.T) {
+	var body mistralRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("got path %s, accept %q", r.URL.Path, r.Header.Get("Accept"))
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		// Magistral sends thinking chunks; the stream ends without [DONE]
+		fmt.Fprint(w, `data: {"id":"cmpl-1","model":"magistral-medium-2509","choices":[{"delta":{"content":[{"type":"thinking","thinking":[{"type":"text","text":"Adding."}]}]}}]}`+"\n\n"+
+			`data: {"id":"cmpl-1","model":"magistral-medium-2509","choices":[{"delta":{"content":[{"type":"text","text":"Hel"}]}}]}`+"\n\n"+
+			`data: {"id":"cmpl-1","model":"magistral-medium-2509","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}],"usage":{"prompt_tokens":6,"completion_tokens":3}}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("MISTRAL_API_KEY", "test")
+	m, err := NewMistral(WithMistralBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seed := int64(7)
+	req := Request{Model: "magistral-medium-latest", Prompt: "hi", Params: Params{Seed: &seed}}
+	var streamed string
+	resp, err := m.QueryStream(context.Background(), req, func(chunk string) { streamed += chunk })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !body.Stream || body.RandomSeed == nil || *body.RandomSeed != 7 {
+		t.Errorf("got stream %v, random seed %v", body.Stream, body.RandomSeed)
+	}
+	if resp.Content != "Hello" || streamed != "Hello" || resp.Reasoning != "Adding." {
+		t.Errorf("got content %q, streamed %q, reasoning %q", resp.Content, streamed, resp.Reasoning)
+	}
+	if resp.Usage != (Usage{InputTokens: 6, OutputTokens: 3}) || resp.FinishReason != "stop" {
+		t.Errorf("got usage %+v, finish reason %q", resp.Usage, resp.FinishReason)
+	}
+	if resp.RequestID != "cmpl-1" || resp.ResolvedModel != "magistral-medium-2509" {
+		t.Errorf("got request ID %q, resolved model %q", resp.RequestID, resp.ResolvedModel)
+	}
+}
+
+func TestMistral_QueryStreamEnds(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  string
+		wantErr string // "" for success
+	}{
+		{"done", `data: {"choices":[{"delta":{"content":"Hi"}}]}` + "\n\n" + "data: [DONE]\n\n", ""},
+		{"cut off", `data: {"choices":[{"delta":{"content":"Hi"}}]}` + "\n\n", "stream ended before the response was complete"},
+		{"error event", `data: {"choices":[{"delta":{"content":"Hi"}}]}` + "\n\n" + `data: {"error":{"message":"Service unavailable","type":"service_unavailable"}}` + "\n\n", "Service unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.events)
+			}))
+			defer srv.Close()
+
+			t.Setenv("MISTRAL_API_KEY", "test")
+			m, err := NewMistral(WithMistralBaseURL(srv.URL))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			resp, err := m.QueryStream(context.Background(), Request{Model: "mistral-small-latest", Prompt: "hi"}, nil)
+			if tt.wantErr != "" {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Message != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				if resp.Provider != "mistral" {
+					t.Errorf("got response %+v, want the partial result", resp)
+				}
+				return
+			}
+			if err != nil || resp.Content != "Hi" {
+				t.Errorf("got %q, %v", resp.Content, err)
+			}
+		})
+	}
+}
+
+func TestMistral_QueryError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"object":"error","message":"Requests rate limit exceeded","type":"rate_limited"}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv("MISTRAL_API_KEY", "test")
+	m, err := NewMistral(WithMistralBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = m.Query(context.Background(), Request{Model: "mistral-small-latest", Prompt: "hi"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Class != ErrorRateLimit || apiErr.Message != "Requests rate limit exceeded" {
+		t.Errorf("got %v, want a rate limit error with the message", err)
+	}
+}
@@ -0,0 +1,330 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ollama Models
+// Full list: https://ollama.com/library
+//
+// Models are whatever has been pulled locally (see `ollama list`) and are
+// addressed with an "ollama:" prefix, e.g. ollama:llama3 or ollama:qwen2.5:14b.
+// Use ListModels to discover the installed set at runtime.
+
+// OllamaPrefix marks a model name as served by a local Ollama instance.
+const OllamaPrefix = "ollama:"
+
+// Ollama implements Provider for a local or remote Ollama server.
+type Ollama struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// OllamaOption configures an Ollama provider.
+type OllamaOption func(*Ollama)
+
+// WithOllamaBaseURL sets the server URL (default http://localhost:11434).
+func WithOllamaBaseURL(url string) OllamaOption {
+	return func(o *Ollama) { o.baseURL = normalizeOllamaURL(url) }
+}
+
+// WithOllamaHTTPClient sets a custom HTTP client.
+func WithOllamaHTTPClient(c *http.Client) OllamaOption {
+	return func(o *Ollama) { o.httpClient = c }
+}
+
+// NewOllama creates an Ollama provider.
+// Reads the server address from OLLAMA_HOST if set; no API key is needed.
+func NewOllama(opts ...OllamaOption) (*Ollama, error) {
+	baseURL := "http://localhost:11434"
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		baseURL = normalizeOllamaURL(host)
+	}
+
+	o := &Ollama{
+		baseURL:    baseURL,
+		httpClient: defaultHTTPClient,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o, nil
+}
+
+// ListModels returns the names of all models installed on the server,
+// as reported by /api/tags (e.g. "llama3:latest").
+func (o *Ollama) ListModels(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, respBody)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.Unmarshal(respBody, &tags); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// Query sends a prompt to an Ollama model and returns the response.
+func (o *Ollama) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildOllamaRequest(req, false)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if chatResp.Error != "" {
+		return Response{}, streamError("", "", chatResp.Error)
+	}
+
+	if chatResp.Message.Content == "" {
+		return Response{}, errors.New("no content in response")
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Content:   chatResp.Message.Content,
+		Reasoning: chatResp.Message.Thinking,
+		Provider:  "ollama",
+	}
+	chatResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// QueryStream sends a prompt to an Ollama model and streams the response.
+// Ollama streams newline-delimited JSON objects rather than SSE.
+func (o *Ollama) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildOllamaRequest(req, true)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:    req.Model,
+		Provider: "ollama",
+	}
+
+	var fullContent, reasoning strings.Builder
+	// The stream is newline-delimited JSON, which a decoder reads
+	// whatever the line length
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaChatResponse
+		if err := dec.Decode(&chunk); err != nil {
+			return result, streamEnded(ctx, err, "")
+		}
+
+		// Errors after the headers are sent arrive as a JSON line
+		if chunk.Error != "" {
+			return result, streamError("", "", chunk.Error)
+		}
+
+		reasoning.WriteString(chunk.Message.Thinking)
+		if chunk.Message.Content != "" {
+			fullContent.WriteString(chunk.Message.Content)
+			if callback != nil {
+				callback(chunk.Message.Content)
+			}
+		}
+
+		if chunk.Done {
+			chunk.fill(&result)
+			break
+		}
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// OllamaModelName strips the "ollama:" prefix from a model name.
+func OllamaModelName(model string) string {
+	return strings.TrimPrefix(model, OllamaPrefix)
+}
+
+// normalizeOllamaURL accepts the forms OLLAMA_HOST allows ("host:port",
+// "http://host:port") and returns a base URL without a trailing slash.
+func normalizeOllamaURL(host string) string {
+	host = strings.TrimRight(strings.TrimSpace(host), "/")
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return host
+}
+
+// Ollama chat API types
+// https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+
+// buildOllamaRequest maps a Request onto the /api/chat payload. Sampling
+// settings go in "options" using Ollama's modelfile parameter names.
+// Thinking is a plain on/off switch; Ollama has no budget.
+func buildOllamaRequest(req Request, stream bool) ollamaChatRequest {
+	var messages []ollamaMessage
+	if req.System != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Conversation() {
+		messages = append(messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+
+	payload := ollamaChatRequest{
+		Model:    OllamaModelName(req.Model),
+		Messages: messages,
+		Format:   req.Schema,
+		Stream:   stream,
+	}
+
+	if req.ReasoningEffort != "" || req.ThinkingBudget > 0 {
+		think := req.reasoning()
+		payload.Think = &think
+	}
+
+	if req.Temperature != nil || req.TopP != nil || req.MaxTokens != 0 || len(req.Stop) > 0 || req.Seed != nil {
+		payload.Options = &ollamaOptions{
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			NumPredict:  req.MaxTokens,
+			Stop:        req.Stop,
+			Seed:        req.Seed,
+		}
+	}
+
+	return payload
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+	Think    *bool           `json:"think,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"` // JSON Schema for structured output
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Thinking string `json:"thinking,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// fill copies the final response's token counts and stop reason onto resp.
+func (r *ollamaChatResponse) fill(resp *Response) {
+	resp.Usage = Usage{
+		InputTokens:  r.PromptEvalCount,
+		OutputTokens: r.EvalCount,
+	}
+	resp.FinishReason = r.DoneReason
+	resp.ResolvedModel = r.Model
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
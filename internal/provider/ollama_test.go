@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestOllama_ListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/tags" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3:latest","model":"llama3:latest"},{"name":"qwen2.5:14b","model":"qwen2.5:14b"}]}`)
+	}))
+	defer srv.Close()
+
+	// OLLAMA_HOST may be given without a scheme
+	t.Setenv("OLLAMA_HOST", srv.Listener.Addr().String())
+	o, err := NewOllama()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	models, err := o.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"llama3:latest", "qwen2.5:14b"}; !slices.Equal(models, want) {
+		t.Errorf("got models %v, want %v", models, want)
+	}
+}
+
+func TestOllama_QueryStream(t *testing.T) {
+	var body ollamaChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("got path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"model":"qwen3:8b","message":{"role":"assistant","content":"","thinking":"Adding."},"done":false}`+"\n"+
+			`{"model":"qwen3:8b","message":{"role":"assistant","content":"Hel"},"done":false}`+"\n"+
+			`{"model":"qwen3:8b","message":{"role":"assistant","content":"lo"},"done":false}`+"\n"+
+			`{"model":"qwen3:8b","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":11,"eval_count":4}`+"\n")
+	}))
+	defer srv.Close()
+
+	o, err := NewOllama(WithOllamaBaseURL(srv.URL + "/"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	temp := 0.2
+	req := Request{
+		Model:  "ollama:qwen3:8b",
+		Prompt: "hi",
+		Params: Params{System: "Be brief.", Temperature: &temp, MaxTokens: 64, ReasoningEffort: "high"},
+	}
+	var streamed string
+	resp, err := o.QueryStream(context.Background(), req, func(chunk string) { streamed += chunk })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if body.Model != "qwen3:8b" || !body.Stream || len(body.Messages) != 2 || body.Messages[0].Role != "system" {
+		t.Errorf("got model %q, stream %v, messages %+v", body.Model, body.Stream, body.Messages)
+	}
+	if body.Options == nil || *body.Options.Temperature != 0.2 || body.Options.NumPredict != 64 || body.Think == nil || !*body.Think {
+		t.Errorf("got options %+v, think %v", body.Options, body.Think)
+	}
+
+	if resp.Content != "Hello" || streamed != "Hello" || resp.Reasoning != "Adding." {
+		t.Errorf("got content %q, streamed %q, reasoning %q", resp.Content, streamed, resp.Reasoning)
+	}
+	if resp.Usage != (Usage{InputTokens: 11, OutputTokens: 4}) || !resp.Truncated() || resp.ResolvedModel != "qwen3:8b" {
+		t.Errorf("got usage %+v, finish reason %q, resolved model %q", resp.Usage, resp.FinishReason, resp.ResolvedModel)
+	}
+}
+
+func TestOllama_QueryStreamErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines string
+	}{
+		{"error line", `{"model":"llama3","message":{"content":"Hi"},"done":false}` + "\n" + `{"error":"model runner has unexpectedly stopped"}` + "\n"},
+		{"cut off before done", `{"model":"llama3","message":{"content":"Hi"},"done":false}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.lines)
+			}))
+			defer srv.Close()
+
+			o, err := NewOllama(WithOllamaBaseURL(srv.URL))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := o.QueryStream(context.Background(), Request{Model: "ollama:llama3", Prompt: "hi"}, nil); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -43,6 +42,12 @@ type OpenAI struct {
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+
+	// Hooks for Responses-compatible variants such as Azure OpenAI.
+	name      string                                           // reported as Response.Provider
+	prefix    string                                           // stripped from model names, e.g. "azure:"
+	query     string                                           // appended to the URL, e.g. "?api-version=..."
+	authorize func(ctx context.Context, r *http.Request) error // sets auth headers; nil uses the bearer API key
 }
 
 // OpenAIOption configures an OpenAI provider.
@@ -69,7 +74,8 @@ func NewOpenAI(opts ...OpenAIOption) (*OpenAI, error) {
 	o := &OpenAI{
 		apiKey:     apiKey,
 		baseURL:    "https://api.openai.com/v1",
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		httpClient: defaultHTTPClient,
+		name:       "openai",
 	}
 
 	for _, opt := range opts {
@@ -84,27 +90,21 @@ func NewOpenAI(opts ...OpenAIOption) (*OpenAI, error) {
 func (o *OpenAI) Query(ctx context.Context, req Request) (Response, error) {
 	start := time.Now()
 
-	payload := responsesRequest{
-		Model: req.Model,
-		Input: req.Prompt,
-	}
+	payload := o.buildRequest(req, false)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return Response{}, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/responses", bytes.NewReader(body))
+	httpReq, err := o.newRequest(ctx, body)
 	if err != nil {
-		return Response{}, fmt.Errorf("creating request: %w", err)
+		return Response{}, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
-
 	resp, err := o.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
@@ -114,7 +114,7 @@ func (o *OpenAI) Query(ctx context.Context, req Request) (Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
 	var responsesResp responsesResponse
@@ -124,16 +124,22 @@ func (o *OpenAI) Query(ctx context.Context, req Request) (Response, error) {
 
 	// Extract text from output items
 	content := extractResponseText(responsesResp.Output)
-	if content == "" {
+	calls := extractFunctionCalls(responsesResp.Output)
+	if content == "" && len(calls) == 0 {
 		return Response{}, errors.New("no content in response")
 	}
 
-	return Response{
-		Model:    req.Model,
-		Content:  content,
-		Provider: "openai",
-		Latency:  time.Since(start),
-	}, nil
+	result := Response{
+		Model:     req.Model,
+		Content:   content,
+		Reasoning: extractReasoningSummary(responsesResp.Output),
+		Provider:  o.name,
+		RequestID: requestID(resp.Header, "x-request-id", responsesResp.ID),
+		ToolCalls: calls,
+	}
+	responsesResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
 }
 
 // QueryStream sends a prompt to an OpenAI model and streams the response.
@@ -141,98 +147,317 @@ func (o *OpenAI) Query(ctx context.Context, req Request) (Response, error) {
 func (o *OpenAI) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
 	start := time.Now()
 
-	payload := responsesStreamRequest{
-		Model:  req.Model,
-		Input:  req.Prompt,
-		Stream: true,
-	}
+	payload := o.buildRequest(req, true)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return Response{}, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/responses", bytes.NewReader(body))
+	httpReq, err := o.newRequest(ctx, body)
 	if err != nil {
-		return Response{}, fmt.Errorf("creating request: %w", err)
+		return Response{}, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
-
 	resp, err := o.httpClient.Do(httpReq)
 	if err != nil {
-		return Response{}, fmt.Errorf("sending request: %w", err)
+		return Response{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return Response{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return Response{}, newAPIError(resp, respBody)
 	}
 
-	var fullContent strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+	result := Response{
+		Model:     req.Model,
+		Provider:  o.name,
+		RequestID: resp.Header.Get("x-request-id"),
+	}
+
+	var fullContent, reasoning strings.Builder
+	events := newSSEReader(resp.Body)
+	for done := false; !done; {
+		ev, err := events.Next()
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
 		}
-		data := strings.TrimPrefix(line, "data: ")
-		if data == "[DONE]" {
-			break
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
 		}
 
 		var event responsesStreamEvent
-		if err := json.Unmarshal([]byte(data), &event); err != nil {
+		if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case "response.output_text.delta":
+			if event.Delta != "" {
+				fullContent.WriteString(event.Delta)
+				if callback != nil {
+					callback(event.Delta)
+				}
+			}
+		case "response.reasoning_summary_text.delta":
+			reasoning.WriteString(event.Delta)
+		case "response.reasoning_summary_part.added":
+			// Separate summary parts the way the non-streaming path does
+			if reasoning.Len() > 0 {
+				reasoning.WriteString("\n\n")
+			}
+		case "response.failed":
+			apiErr := streamError("", "", "response failed")
+			if event.Response != nil && event.Response.Error != nil {
+				apiErr = streamError("", event.Response.Error.Code, event.Response.Error.Message)
+			}
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		case "response.completed", "response.incomplete":
+			done = true
+			// The final snapshot carries usage, the stop status and any
+			// complete function calls
+			if event.Response != nil {
+				event.Response.fill(&result)
+				result.ToolCalls = extractFunctionCalls(event.Response.Output)
+				if result.RequestID == "" {
+					result.RequestID = event.Response.ID
+				}
+			}
+		}
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// buildRequest maps a Request onto the Responses API payload. The
+// conversation is sent as input messages, which accept all three roles.
+// The Responses API has no stop sequences or seed, so those are not sent.
+// Reasoning models only expose a summary of their reasoning, which is
+// requested whenever an effort level is set. Tool turns become
+// function_call and function_call_output items after the prompt; with
+// NoToolCalls, tool_choice "none" keeps the model from calling more.
+func (o *OpenAI) buildRequest(req Request, stream bool) responsesRequest {
+	var input []responsesInputMessage
+	for _, m := range req.Conversation() {
+		if len(m.Attachments) == 0 {
+			input = append(input, responsesInputMessage{Role: m.Role, Content: m.Content})
 			continue
 		}
 
-		// Handle text delta events
-		if event.Type == "response.output_text.delta" && event.Delta != "" {
-			fullContent.WriteString(event.Delta)
-			if callback != nil {
-				callback(event.Delta)
+		// Images and files go in their turn as typed content parts
+		parts := []responsesInputContent{{Type: "input_text", Text: m.Content}}
+		for _, a := range m.Attachments {
+			if isImage(a.MIMEType) {
+				parts = append(parts, responsesInputContent{Type: "input_image", ImageURL: a.dataURL()})
+			} else {
+				parts = append(parts, responsesInputContent{Type: "input_file", Filename: a.Name, FileData: a.dataURL()})
 			}
 		}
+		input = append(input, responsesInputMessage{Role: m.Role, Content: parts})
+	}
+
+	for _, m := range req.ToolTurns {
+		if m.Content != "" {
+			input = append(input, responsesInputMessage{Role: m.Role, Content: m.Content})
+		}
+		for _, c := range m.ToolCalls {
+			input = append(input, responsesInputMessage{Type: "function_call", CallID: c.ID, Name: c.Name, Arguments: string(c.toolArguments())})
+		}
+		for _, r := range m.ToolResults {
+			input = append(input, responsesInputMessage{Type: "function_call_output", CallID: r.CallID, Output: r.Content})
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return Response{}, fmt.Errorf("reading stream: %w", err)
+	var (
+		tools      []responsesTool
+		toolChoice string
+	)
+	for _, t := range req.Tools {
+		tools = append(tools, responsesTool{Type: "function", Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+	}
+	if len(tools) > 0 && req.NoToolCalls {
+		toolChoice = "none"
+	}
+
+	var reasoning *responsesReasoning
+	if req.ReasoningEffort != "" {
+		reasoning = &responsesReasoning{Effort: req.ReasoningEffort}
+		if req.ReasoningEffort != "none" {
+			reasoning.Summary = "auto"
+		}
+	}
+
+	var text *responsesText
+	if req.Schema != nil {
+		text = &responsesText{Format: responsesFormat{Type: "json_schema", Name: schemaName, Schema: req.Schema}}
 	}
 
-	return Response{
-		Model:    req.Model,
-		Content:  fullContent.String(),
-		Provider: "openai",
-		Latency:  time.Since(start),
-	}, nil
+	return responsesRequest{
+		Model:           strings.TrimPrefix(req.Model, o.prefix),
+		Input:           input,
+		Instructions:    req.System,
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		MaxOutputTokens: req.MaxTokens,
+		Reasoning:       reasoning,
+		Text:            text,
+		Tools:           tools,
+		ToolChoice:      toolChoice,
+		Stream:          stream,
+	}
+}
+
+// SupportsTools reports that the Responses API accepts function tools.
+func (o *OpenAI) SupportsTools(model string) bool {
+	return true
+}
+
+// SupportsAttachment reports whether the Responses API accepts the MIME
+// type: common image formats as input_image and PDFs as input_file.
+func (o *OpenAI) SupportsAttachment(model, mimeType string) bool {
+	return isImage(mimeType) || mimeType == "application/pdf"
+}
+
+// newRequest builds an authenticated POST to the Responses endpoint.
+func (o *OpenAI) newRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/responses"+o.query, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.authorize != nil {
+		if err := o.authorize(ctx, httpReq); err != nil {
+			return nil, fmt.Errorf("authorizing request: %w", err)
+		}
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	return httpReq, nil
 }
 
 // Responses API types (recommended for GPT-5 and reasoning models)
 // https://platform.openai.com/docs/api-reference/responses
 
 type responsesRequest struct {
-	Model        string `json:"model"`
-	Input        string `json:"input"`
-	Instructions string `json:"instructions,omitempty"`
+	Model           string                  `json:"model"`
+	Input           []responsesInputMessage `json:"input"`
+	Instructions    string                  `json:"instructions,omitempty"`
+	Temperature     *float64                `json:"temperature,omitempty"`
+	TopP            *float64                `json:"top_p,omitempty"`
+	MaxOutputTokens int                     `json:"max_output_tokens,omitempty"`
+	Reasoning       *responsesReasoning     `json:"reasoning,omitempty"`
+	Text            *responsesText          `json:"text,omitempty"`
+	Tools           []responsesTool         `json:"tools,omitempty"`
+	ToolChoice      string                  `json:"tool_choice,omitempty"`
+	Stream          bool                    `json:"stream,omitempty"`
+}
+
+type responsesTool struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type responsesText struct {
+	Format responsesFormat `json:"format"`
+}
+
+// responsesFormat requests structured output. Strict mode is left off
+// because it rejects schemas that don't mark every property required;
+// answers are validated locally instead.
+type responsesFormat struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+type responsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// responsesInputMessage is an input item: a message, whose content is a
+// string or []responsesInputContent when the turn carries attachments, or
+// a function_call or function_call_output item.
+type responsesInputMessage struct {
+	Type      string `json:"type,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Content   any    `json:"content,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
 }
 
-type responsesStreamRequest struct {
-	Model        string `json:"model"`
-	Input        string `json:"input"`
-	Instructions string `json:"instructions,omitempty"`
-	Stream       bool   `json:"stream"`
+type responsesInputContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
 }
 
 type responsesResponse struct {
-	ID     string            `json:"id"`
+	ID                string `json:"id"`
+	Model             string `json:"model"`
+	Status            string `json:"status"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
 	Output []responsesOutput `json:"output"`
+	Usage  struct {
+		InputTokens        int `json:"input_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+		OutputTokens        int `json:"output_tokens"`
+		OutputTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+	} `json:"usage"`
+	Error *responsesError `json:"error"`
+}
+
+// responsesError is the error of a failed response.
+type responsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// fill copies usage, stop status and the resolved model onto resp.
+// An incomplete response reports why it stopped (e.g. "max_output_tokens").
+func (r *responsesResponse) fill(resp *Response) {
+	resp.ResolvedModel = r.Model
+	resp.FinishReason = r.Status
+	if r.IncompleteDetails != nil && r.IncompleteDetails.Reason != "" {
+		resp.FinishReason = r.IncompleteDetails.Reason
+	}
+	resp.Usage = Usage{
+		InputTokens:     r.Usage.InputTokens,
+		OutputTokens:    r.Usage.OutputTokens,
+		ReasoningTokens: r.Usage.OutputTokensDetails.ReasoningTokens,
+		CachedTokens:    r.Usage.InputTokensDetails.CachedTokens,
+	}
 }
 
 type responsesOutput struct {
 	Type    string             `json:"type"`
 	Content []responsesContent `json:"content,omitempty"`
+	Summary []responsesContent `json:"summary,omitempty"` // reasoning items
+
+	// function_call items
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 type responsesContent struct {
@@ -241,8 +466,9 @@ type responsesContent struct {
 }
 
 type responsesStreamEvent struct {
-	Type  string `json:"type"`
-	Delta string `json:"delta,omitempty"`
+	Type     string             `json:"type"`
+	Delta    string             `json:"delta,omitempty"`
+	Response *responsesResponse `json:"response,omitempty"`
 }
 
 // extractResponseText extracts text content from Responses API output.
@@ -259,3 +485,31 @@ func extractResponseText(outputs []responsesOutput) string {
 	}
 	return result.String()
 }
+
+// extractReasoningSummary joins the reasoning summary parts from Responses
+// API output, one paragraph per part.
+func extractReasoningSummary(outputs []responsesOutput) string {
+	var parts []string
+	for _, output := range outputs {
+		if output.Type == "reasoning" {
+			for _, s := range output.Summary {
+				if s.Type == "summary_text" {
+					parts = append(parts, s.Text)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// extractFunctionCalls returns the function_call items in Responses API
+// output.
+func extractFunctionCalls(outputs []responsesOutput) []ToolCall {
+	var calls []ToolCall
+	for _, output := range outputs {
+		if output.Type == "function_call" {
+			calls = append(calls, ToolCall{ID: output.CallID, Name: output.Name, Arguments: rawArguments(output.Arguments)})
+		}
+	}
+	return calls
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenRouter Models
+// Full list: https://openrouter.ai/models (synced by cmd/model-registry-sync)
+//
+// Models are addressed as "openrouter/<vendor>/<model>", e.g.:
+//   - openrouter/anthropic/claude-sonnet-4.5
+//   - openrouter/google/gemini-2.5-pro
+//   - openrouter/meta-llama/llama-3.3-70b-instruct
+//   - openrouter/deepseek/deepseek-r1
+
+// OpenRouterPrefix marks a model name as routed through OpenRouter.
+const OpenRouterPrefix = "openrouter/"
+
+// OpenRouterRouting holds provider-routing preferences sent with every request.
+// See https://openrouter.ai/docs/features/provider-routing
+type OpenRouterRouting struct {
+	Order          []string `json:"order,omitempty"`           // upstream providers to try, in order
+	AllowFallbacks *bool    `json:"allow_fallbacks,omitempty"` // nil uses OpenRouter's default (true)
+	DataCollection string   `json:"data_collection,omitempty"` // "allow" or "deny"
+}
+
+// OpenRouter implements Provider for OpenRouter's OpenAI-compatible API.
+type OpenRouter struct {
+	apiKey     string
+	baseURL    string
+	referer    string
+	title      string
+	routing    *OpenRouterRouting
+	httpClient *http.Client
+}
+
+// OpenRouterOption configures an OpenRouter provider.
+type OpenRouterOption func(*OpenRouter)
+
+// WithOpenRouterBaseURL sets a custom base URL.
+func WithOpenRouterBaseURL(url string) OpenRouterOption {
+	return func(o *OpenRouter) { o.baseURL = url }
+}
+
+// WithOpenRouterHTTPClient sets a custom HTTP client.
+func WithOpenRouterHTTPClient(c *http.Client) OpenRouterOption {
+	return func(o *OpenRouter) { o.httpClient = c }
+}
+
+// WithOpenRouterAttribution sets the HTTP-Referer and X-Title headers
+// OpenRouter uses to attribute traffic to an app.
+func WithOpenRouterAttribution(referer, title string) OpenRouterOption {
+	return func(o *OpenRouter) {
+		o.referer = referer
+		o.title = title
+	}
+}
+
+// WithOpenRouterRouting sets provider-routing preferences.
+func WithOpenRouterRouting(r OpenRouterRouting) OpenRouterOption {
+	return func(o *OpenRouter) { o.routing = &r }
+}
+
+// NewOpenRouter creates an OpenRouter provider.
+// Reads API key from OPENROUTER_API_KEY environment variable.
+func NewOpenRouter(opts ...OpenRouterOption) (*OpenRouter, error) {
+	apiKey := os.Getenv("OPENROUTER_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("OPENROUTER_API_KEY environment variable required")
+	}
+
+	o := &OpenRouter{
+		apiKey:     apiKey,
+		baseURL:    "https://openrouter.ai/api/v1",
+		referer:    "https://github.com/johnayoung/llm-consensus",
+		title:      "llm-consensus",
+		httpClient: defaultHTTPClient,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o, nil
+}
+
+// Query sends a prompt to an OpenRouter model and returns the response.
+func (o *OpenRouter) Query(ctx context.Context, req Request) (Response, error) {
+	start := time.Now()
+
+	payload := buildOpenRouterRequest(req, o.routing, false)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	o.setHeaders(httpReq)
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	var orResp openRouterResponse
+	if err := json.Unmarshal(respBody, &orResp); err != nil {
+		return Response{}, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if len(orResp.Choices) == 0 || orResp.Choices[0].Message.Content == "" {
+		return Response{}, errors.New("no content in response")
+	}
+
+	result := Response{
+		Model:     req.Model,
+		Content:   orResp.Choices[0].Message.Content,
+		Reasoning: orResp.Choices[0].Message.reasoning(),
+		Provider:  "openrouter",
+		Upstream:  orResp.Provider,
+	}
+	orResp.fill(&result)
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// QueryStream sends a prompt to an OpenRouter model and streams the response.
+func (o *OpenRouter) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	start := time.Now()
+
+	payload := buildOpenRouterRequest(req, o.routing, true)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	o.setHeaders(httpReq)
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, respBody)
+	}
+
+	result := Response{
+		Model:    req.Model,
+		Provider: "openrouter",
+	}
+
+	var fullContent, reasoning strings.Builder
+	// OpenRouter sends ": OPENROUTER PROCESSING" comments while queued,
+	// which the decoder skips
+	events := newSSEReader(resp.Body)
+	for {
+		ev, err := events.Next()
+		if errors.Is(err, io.EOF) && result.FinishReason != "" {
+			break // not every server sends [DONE]
+		}
+		if err != nil {
+			return result, streamEnded(ctx, err, result.RequestID)
+		}
+		if ev.Data == "[DONE]" {
+			break
+		}
+		if apiErr := errorEvent(ev); apiErr != nil {
+			apiErr.RequestID = result.RequestID
+			return result, apiErr
+		}
+
+		var chunk openRouterResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			continue
+		}
+
+		if chunk.Provider != "" {
+			result.Upstream = chunk.Provider
+		}
+
+		if len(chunk.Choices) > 0 {
+			delta := chunk.Choices[0].Delta
+			reasoning.WriteString(delta.reasoning())
+			if delta.Content != "" {
+				fullContent.WriteString(delta.Content)
+				if callback != nil {
+					callback(delta.Content)
+				}
+			}
+		}
+
+		chunk.fill(&result)
+	}
+
+	result.Content = fullContent.String()
+	result.Reasoning = reasoning.String()
+	result.Latency = time.Since(start)
+	return result, nil
+}
+
+// OpenRouterModelName strips the "openrouter/" prefix, leaving the
+// "<vendor>/<model>" slug OpenRouter expects.
+func OpenRouterModelName(model string) string {
+	return strings.TrimPrefix(model, OpenRouterPrefix)
+}
+
+func (o *OpenRouter) setHeaders(httpReq *http.Request) {
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	if o.referer != "" {
+		httpReq.Header.Set("HTTP-Referer", o.referer)
+	}
+	if o.title != "" {
+		httpReq.Header.Set("X-Title", o.title)
+	}
+}
+
+// OpenRouter extends the Chat Completions types with routing preferences
+// and the upstream provider that served the request.
+// https://openrouter.ai/docs/api-reference/chat-completion
+
+// buildOpenRouterRequest maps a Request onto the Chat Completions payload
+// plus routing preferences. Reasoning settings use OpenRouter's unified
+// "reasoning" object, which it translates for each upstream.
+func buildOpenRouterRequest(req Request, routing *OpenRouterRouting, stream bool) openRouterRequest {
+	payload := openRouterRequest{
+		chatCompletionsRequest: buildChatCompletionsRequest(OpenRouterModelName(req.Model), req, stream),
+		Provider:               routing,
+	}
+
+	payload.ReasoningEffort = ""
+	if req.ThinkingBudget > 0 {
+		payload.Reasoning = &openRouterReasoning{MaxTokens: req.ThinkingBudget}
+	} else if req.ReasoningEffort != "" {
+		payload.Reasoning = &openRouterReasoning{Effort: req.ReasoningEffort}
+	}
+
+	return payload
+}
+
+type openRouterRequest struct {
+	chatCompletionsRequest
+	Provider  *OpenRouterRouting   `json:"provider,omitempty"`
+	Reasoning *openRouterReasoning `json:"reasoning,omitempty"`
+}
+
+type openRouterReasoning struct {
+	Effort    string `json:"effort,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+type openRouterResponse struct {
+	chatCompletionsResponse
+	Provider string `json:"provider"`
+}
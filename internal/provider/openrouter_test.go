@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestOpenRouter_Query(t *testing.T) {
+	var (
+		body   openRouterRequest
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"id":"gen-1","model":"anthropic/claude-sonnet-4.5","provider":"Amazon Bedrock",`+
+			`"choices":[{"message":{"role":"assistant","content":"4","reasoning":"2+2"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":3}}`)
+	}))
+	defer srv.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test")
+	noFallbacks := false
+	o, err := NewOpenRouter(
+		WithOpenRouterBaseURL(srv.URL),
+		WithOpenRouterRouting(OpenRouterRouting{Order: []string{"anthropic", "amazon-bedrock"}, AllowFallbacks: &noFallbacks}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := Request{Model: "openrouter/anthropic/claude-sonnet-4.5", Prompt: "2+2?", Params: Params{ReasoningEffort: "high"}}
+	resp, err := o.Query(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if header.Get("Authorization") != "Bearer test" || header.Get("X-Title") != "llm-consensus" || header.Get("HTTP-Referer") == "" {
+		t.Errorf("got headers %v", header)
+	}
+	if body.Model != "anthropic/claude-sonnet-4.5" {
+		t.Errorf("got model %q", body.Model)
+	}
+	if body.Provider == nil || !slices.Equal(body.Provider.Order, []string{"anthropic", "amazon-bedrock"}) ||
+		body.Provider.AllowFallbacks == nil || *body.Provider.AllowFallbacks {
+		t.Errorf("got provider preferences %+v", body.Provider)
+	}
+	// Effort goes in the unified reasoning object, not reasoning_effort
+	if body.Reasoning == nil || body.Reasoning.Effort != "high" || body.ReasoningEffort != "" {
+		t.Errorf("got reasoning %+v, reasoning_effort %q", body.Reasoning, body.ReasoningEffort)
+	}
+
+	if resp.Content != "4" || resp.Reasoning != "2+2" || resp.Upstream != "Amazon Bedrock" {
+		t.Errorf("got content %q, reasoning %q, upstream %q", resp.Content, resp.Reasoning, resp.Upstream)
+	}
+	if resp.Usage != (Usage{InputTokens: 9, OutputTokens: 3}) || resp.RequestID != "gen-1" {
+		t.Errorf("got usage %+v, request ID %q", resp.Usage, resp.RequestID)
+	}
+}
+
+func TestOpenRouter_QueryStream(t *testing.T) {
+	var body openRouterRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n"+
+			`data: {"id":"gen-1","provider":"DeepInfra","model":"deepseek/deepseek-r1","choices":[{"delta":{"content":"Hel"}}]}`+"\n\n"+
+			`data: {"id":"gen-1","provider":"DeepInfra","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`+"\n\n"+
+			`data: {"id":"gen-1","provider":"DeepInfra","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2}}`+"\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test")
+	o, err := NewOpenRouter(WithOpenRouterBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := Request{Model: "openrouter/deepseek/deepseek-r1", Prompt: "hi", Params: Params{ThinkingBudget: 1024}}
+	resp, err := o.QueryStream(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if body.Provider != nil {
+		t.Errorf("sent provider preferences %+v with none set", body.Provider)
+	}
+	if body.Reasoning == nil || body.Reasoning.MaxTokens != 1024 || body.Reasoning.Effort != "" {
+		t.Errorf("got reasoning %+v, want a token budget", body.Reasoning)
+	}
+	if resp.Content != "Hello" || resp.Upstream != "DeepInfra" || resp.Usage != (Usage{InputTokens: 5, OutputTokens: 2}) {
+		t.Errorf("got content %q, upstream %q, usage %+v", resp.Content, resp.Upstream, resp.Usage)
+	}
+}
@@ -2,6 +2,9 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,7 +19,9 @@ type Provider interface {
 
 	// QueryStream sends a prompt and streams the response via callback.
 	// The callback is invoked for each chunk of text received.
-	// Returns the complete response when finished.
+	// Returns the complete response when finished. A stream cut off
+	// partway returns what is known so far, such as the provider, request
+	// ID and usage reported before the cut, along with the error.
 	QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error)
 }
 
@@ -24,14 +29,228 @@ type Provider interface {
 type Request struct {
 	Model  string
 	Prompt string
+
+	// Messages holds prior conversation turns, oldest first. Prompt is sent
+	// after them as the final user turn.
+	Messages []Message
+
+	// Attachments are sent with the prompt turn.
+	Attachments []Attachment
+
+	// Schema, when set, is a JSON Schema the answer must conform to.
+	// Providers pass it to their native structured-output feature and
+	// return the JSON document as Content.
+	Schema json.RawMessage
+
+	// Tools are offered to providers that implement ToolCaller. ToolTurns
+	// follow the prompt: the model's earlier tool calls (assistant turns
+	// with ToolCalls) and their results (RoleTool turns).
+	Tools     []Tool
+	ToolTurns []Message
+
+	// NoToolCalls asks for a final answer: Tools stay declared, as APIs
+	// require for the calls in ToolTurns, but the model may not call them.
+	NoToolCalls bool
+
+	Params
+}
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a single role-tagged conversation turn.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`   // assistant turns
+	ToolResults []ToolResult `json:"tool_results,omitempty"` // RoleTool turns
+
+	// Thinking is the signed reasoning that preceded ToolCalls
+	Thinking []ThinkingBlock `json:"-"`
+
+	// Attachments are the binary files sent with a user turn
+	Attachments []Attachment `json:"-"`
+}
+
+// Conversation returns Messages followed by Prompt as a user turn, with
+// any text attachments inlined into it and the binary ones set as its
+// Attachments, which providers that accept them encode with the turn.
+// ToolTurns are not included; providers that call tools append them
+// after it.
+func (r Request) Conversation() []Message {
+	msgs := make([]Message, 0, len(r.Messages)+1)
+	msgs = append(msgs, r.Messages...)
+	if r.Prompt != "" {
+		msgs = append(msgs, Message{Role: RoleUser, Content: r.promptText(), Attachments: r.binaryAttachments()})
+	}
+	return msgs
+}
+
+// splitSystem separates the conversation for APIs that take the system
+// prompt as a top-level field. Params.System and any system-role messages
+// are joined, in order, into one system prompt.
+func (r Request) splitSystem() (string, []Message) {
+	var (
+		system []string
+		turns  []Message
+	)
+	if r.System != "" {
+		system = append(system, r.System)
+	}
+	for _, m := range r.Conversation() {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		turns = append(turns, m)
+	}
+	return strings.Join(system, "\n\n"), turns
+}
+
+// schemaName names the schema, or the tool forced to carry it, in APIs
+// that require one.
+const schemaName = "response"
+
+// Params holds optional generation settings. Zero values leave the choice to
+// the provider's default. Providers map each field to their native request
+// field and ignore fields their API has no equivalent for.
+type Params struct {
+	System      string   `json:"system,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+
+	// ReasoningEffort is one of "none", "minimal", "low", "medium" or "high".
+	// ThinkingBudget caps reasoning tokens for APIs that take a budget; when
+	// unset it is derived from ReasoningEffort.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ThinkingBudget  int    `json:"thinking_budget,omitempty"`
+}
+
+// effortBudgets maps reasoning effort levels to thinking token budgets for
+// APIs that only accept a budget.
+var effortBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     4096,
+	"medium":  10240,
+	"high":    24576,
+}
+
+// thinkingBudget returns the thinking token budget to request, or 0 when
+// reasoning was not asked for.
+func (p Params) thinkingBudget() int {
+	if p.ThinkingBudget > 0 {
+		return p.ThinkingBudget
+	}
+	return effortBudgets[p.ReasoningEffort]
+}
+
+// reasoning reports whether the request asks for model reasoning.
+func (p Params) reasoning() bool {
+	return p.thinkingBudget() > 0 || (p.ReasoningEffort != "" && p.ReasoningEffort != "none")
+}
+
+// Merge returns a copy of p with every field that is set in override
+// replacing the corresponding field of p.
+func (p Params) Merge(override Params) Params {
+	if override.System != "" {
+		p.System = override.System
+	}
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.MaxTokens != 0 {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.Stop != nil {
+		p.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.ReasoningEffort != "" {
+		p.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.ThinkingBudget != 0 {
+		p.ThinkingBudget = override.ThinkingBudget
+	}
+	return p
 }
 
 // Response contains the result of an LLM query.
 type Response struct {
-	Model    string        `json:"model"`
-	Content  string        `json:"content"`
-	Provider string        `json:"provider"`
-	Latency  time.Duration `json:"latency_ms"`
+	Model         string        `json:"model"`
+	Content       string        `json:"content"`
+	Reasoning     string        `json:"reasoning,omitempty"` // thinking or reasoning summary, kept apart from the answer
+	Provider      string        `json:"provider"`
+	Upstream      string        `json:"upstream,omitempty"` // provider that served a routed request (e.g. via OpenRouter)
+	Latency       time.Duration `json:"latency_ms"`
+	Usage         Usage         `json:"usage,omitzero"`
+	FinishReason  string        `json:"finish_reason,omitempty"`  // as reported by the API, e.g. "end_turn", "MAX_TOKENS"
+	RequestID     string        `json:"request_id,omitempty"`     // provider request or response ID, for support tickets
+	ResolvedModel string        `json:"resolved_model,omitempty"` // model snapshot that served the request
+
+	// Incomplete marks an answer cut off by a timeout or cancellation;
+	// Content holds the text streamed until then
+	Incomplete       bool   `json:"incomplete,omitempty"`
+	IncompleteReason string `json:"incomplete_reason,omitempty"`
+
+	// ToolCalls are the calls the model asked for instead of, or before,
+	// answering. Transcript records the tool exchange that led to a final
+	// answer when a tool loop ran.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Transcript []Message  `json:"transcript,omitempty"`
+
+	// Thinking holds the signed reasoning to replay with ToolCalls
+	Thinking []ThinkingBlock `json:"-"`
+}
+
+// Truncated reports whether the answer was cut off by an output token limit.
+func (r Response) Truncated() bool {
+	switch r.FinishReason {
+	case "length", "max_tokens", "max_output_tokens", "model_length", "MAX_TOKENS":
+		return true
+	}
+	return false
+}
+
+// requestID returns the named response header, falling back to an ID taken
+// from the response body.
+func requestID(h http.Header, name, fallback string) string {
+	if id := h.Get(name); id != "" {
+		return id
+	}
+	return fallback
+}
+
+// Usage reports token counts for a query. InputTokens includes CachedTokens
+// and OutputTokens includes ReasoningTokens.
+type Usage struct {
+	InputTokens     int `json:"input_tokens"`
+	OutputTokens    int `json:"output_tokens"`
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	CachedTokens    int `json:"cached_tokens,omitempty"`
+}
+
+// Add returns the sum of u and other.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:     u.InputTokens + other.InputTokens,
+		OutputTokens:    u.OutputTokens + other.OutputTokens,
+		ReasoningTokens: u.ReasoningTokens + other.ReasoningTokens,
+		CachedTokens:    u.CachedTokens + other.CachedTokens,
+	}
 }
 
 // ProviderFunc allows functions to implement Provider (adapter pattern).
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the requests and estimated tokens sent per minute. Zero
+// fields are unlimited.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// Limiter enforces client-side rate limits with token buckets, so that
+// large panels and batches queue instead of hitting vendor limits. Limits
+// are set per provider name (which stands for its API key) and per model;
+// a request waits until every bucket that applies to it has capacity.
+type Limiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*bucket
+	onWait  func(model string, wait time.Duration)
+}
+
+// NewLimiter creates a limiter with no limits set.
+func NewLimiter() *Limiter {
+	return &Limiter{
+		limits:  make(map[string]RateLimit),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// SetLimit limits requests to key, either a provider name such as
+// "anthropic" or an endpoint name, or a model name.
+func (l *Limiter) SetLimit(key string, limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[key] = limit
+	delete(l.buckets, key+"/requests")
+	delete(l.buckets, key+"/tokens")
+}
+
+// OnWait sets a function called when a request for model must wait for
+// capacity, with how long it will wait.
+func (l *Limiter) OnWait(fn func(model string, wait time.Duration)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onWait = fn
+}
+
+// Middleware returns a middleware passing requests for model through the
+// limits for providerName and for model. It returns nil, which Register
+// and Chain skip, when neither is limited.
+func (l *Limiter) Middleware(providerName, model string) Middleware {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var keys []string
+	for _, key := range []string{providerName, model} {
+		if _, ok := l.limits[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return func(p Provider) Provider {
+		return &limited{Provider: p, limiter: l, keys: keys}
+	}
+}
+
+// bucketFor returns the bucket for key and kind ("requests" or "tokens"),
+// or nil if that kind is unlimited. Callers hold l.mu.
+func (l *Limiter) bucketFor(key, kind string) *bucket {
+	name := key + "/" + kind
+	if b, ok := l.buckets[name]; ok {
+		return b
+	}
+	perMinute := l.limits[key].RequestsPerMinute
+	if kind == "tokens" {
+		perMinute = l.limits[key].TokensPerMinute
+	}
+	if perMinute <= 0 {
+		return nil
+	}
+	b := newBucket(float64(perMinute), time.Minute)
+	l.buckets[name] = b
+	return b
+}
+
+// reservation is capacity taken from a set of buckets.
+type reservation struct {
+	requests []*bucket
+	tokens   []*bucket
+	estimate float64
+}
+
+// reserve takes one request and estimate tokens from every bucket for
+// keys, returning how long until all of them have repaid the debt.
+func (l *Limiter) reserve(keys []string, estimate float64) (reservation, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := reservation{estimate: estimate}
+	var wait time.Duration
+	now := time.Now()
+	for _, key := range keys {
+		if b := l.bucketFor(key, "requests"); b != nil {
+			r.requests = append(r.requests, b)
+			wait = max(wait, b.take(now, 1))
+		}
+		if b := l.bucketFor(key, "tokens"); b != nil {
+			r.tokens = append(r.tokens, b)
+			wait = max(wait, b.take(now, estimate))
+		}
+	}
+	return r, wait
+}
+
+// cancel returns the capacity of a request that was never sent.
+func (r reservation) cancel() {
+	for _, b := range r.requests {
+		b.give(1)
+	}
+	for _, b := range r.tokens {
+		b.give(r.estimate)
+	}
+}
+
+// settle corrects the token buckets once the actual usage is known.
+func (r reservation) settle(used int) {
+	if used <= 0 {
+		return
+	}
+	for _, b := range r.tokens {
+		b.give(r.estimate - float64(used))
+	}
+}
+
+// bucket is a token bucket that may go into debt: a request always takes
+// its share at once and then waits until the bucket is back at zero, which
+// queues requests in the order they arrived.
+type bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // per second
+	level    float64
+	last     time.Time
+}
+
+func newBucket(capacity float64, per time.Duration) *bucket {
+	return &bucket{
+		capacity: capacity,
+		rate:     capacity / per.Seconds(),
+		level:    capacity,
+		last:     time.Now(),
+	}
+}
+
+// take removes n, at most the capacity so that any request can
+// eventually pass, and returns how long until the level is non-negative.
+func (b *bucket) take(now time.Time, n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.level = min(b.capacity, b.level+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.rate * float64(time.Second))
+}
+
+// give adds n back, or removes it if negative.
+func (b *bucket) give(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.level = min(b.capacity, b.level+n)
+}
+
+// limited is a Provider whose requests wait for capacity in a Limiter.
+type limited struct {
+	Provider
+	limiter *Limiter
+	keys    []string
+}
+
+func (p *limited) Query(ctx context.Context, req Request) (Response, error) {
+	r, err := p.wait(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := p.Provider.Query(ctx, req)
+	r.settle(resp.Usage.InputTokens + resp.Usage.OutputTokens)
+	return resp, err
+}
+
+func (p *limited) QueryStream(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+	r, err := p.wait(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := p.Provider.QueryStream(ctx, req, callback)
+	r.settle(resp.Usage.InputTokens + resp.Usage.OutputTokens)
+	return resp, err
+}
+
+// wait reserves capacity for req and blocks until it is available.
+func (p *limited) wait(ctx context.Context, req Request) (reservation, error) {
+	r, wait := p.limiter.reserve(p.keys, float64(estimateTokens(req)))
+	if wait <= 0 {
+		return r, nil
+	}
+
+	p.limiter.mu.Lock()
+	onWait := p.limiter.onWait
+	p.limiter.mu.Unlock()
+	if onWait != nil {
+		onWait(req.Model, wait)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.cancel()
+		return r, ctx.Err()
+	case <-timer.C:
+		return r, nil
+	}
+}
+
+// estimateTokens guesses the tokens a request will use before it is sent:
+// about four characters per input token, plus the output limit if set.
+func estimateTokens(req Request) int {
+	chars := len(req.System)
+	for _, m := range req.Conversation() {
+		chars += len(m.Content)
+	}
+	for _, m := range req.ToolTurns {
+		chars += len(m.Content)
+		for _, c := range m.ToolCalls {
+			chars += len(c.Arguments)
+		}
+		for _, r := range m.ToolResults {
+			chars += len(r.Content)
+		}
+	}
+	return chars/4 + req.MaxTokens
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := newBucket(60, time.Minute) // one per second
+	now := b.last
+
+	if wait := b.take(now, 60); wait != 0 {
+		t.Errorf("full bucket: got wait %v", wait)
+	}
+	if wait := b.take(now, 1); wait != time.Second {
+		t.Errorf("empty bucket: got wait %v, want 1s", wait)
+	}
+	// The second request queues behind the first
+	if wait := b.take(now, 2); wait != 3*time.Second {
+		t.Errorf("queued: got wait %v, want 3s", wait)
+	}
+	// Refills with time
+	if wait := b.take(now.Add(10*time.Second), 1); wait != 0 {
+		t.Errorf("after refill: got wait %v", wait)
+	}
+	// Requests larger than the bucket are clamped so they can pass
+	if wait := b.take(now.Add(10*time.Minute), 1000); wait != 0 {
+		t.Errorf("oversized: got wait %v", wait)
+	}
+}
+
+type toolCapable struct{ ProviderFunc }
+
+func (toolCapable) SupportsTools(model string) bool { return true }
+
+func TestLimiter(t *testing.T) {
+	calls := 0
+	p := toolCapable{func(ctx context.Context, req Request) (Response, error) {
+		calls++
+		return Response{Content: "ok", Usage: Usage{InputTokens: 10, OutputTokens: 5}}, nil
+	}}
+
+	l := NewLimiter()
+	if l.Middleware("openai", "gpt") != nil {
+		t.Error("unlimited provider should not be wrapped")
+	}
+
+	l.SetLimit("anthropic", RateLimit{RequestsPerMinute: 1})
+	var waited []string
+	l.OnWait(func(model string, wait time.Duration) {
+		waited = append(waited, model)
+	})
+	wrapped := Chain(l.Middleware("anthropic", "claude"))(p)
+	if !CallsTools(wrapped, "claude") {
+		t.Error("wrapper hides tool support")
+	}
+
+	if _, err := wrapped.Query(context.Background(), Request{Model: "claude", Prompt: "hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(waited) != 0 {
+		t.Errorf("first request waited")
+	}
+
+	// The second request must wait about a minute; give up after a moment
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := wrapped.QueryStream(ctx, Request{Model: "claude", Prompt: "hi"}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want deadline exceeded", err)
+	}
+	if calls != 1 || len(waited) != 1 || waited[0] != "claude" {
+		t.Errorf("got %d calls, waits %v", calls, waited)
+	}
+}
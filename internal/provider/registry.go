@@ -8,8 +8,9 @@ import (
 // Registry maps model names to their providers.
 // Thread-safe for concurrent access during queries.
 type Registry struct {
-	mu        sync.RWMutex
-	providers map[string]Provider
+	mu         sync.RWMutex
+	providers  map[string]Provider
+	middleware []Middleware
 }
 
 // NewRegistry creates an empty registry.
@@ -19,16 +20,26 @@ func NewRegistry() *Registry {
 	}
 }
 
-// Register associates a model name with a provider.
+// Register associates a model name with a provider, decorated by any
+// middlewares for that model. The first middleware is the outermost.
 // Safe to call concurrently.
-func (r *Registry) Register(model string, p Provider) {
+func (r *Registry) Register(model string, p Provider, middlewares ...Middleware) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.providers[model] = p
+	r.providers[model] = Chain(middlewares...)(p)
 }
 
-// Get retrieves the provider for a model.
-// Returns an error if the model is not registered.
+// Use adds middlewares applied to every model, outside any registered
+// for a single model. They take effect for providers returned by later
+// calls to Get.
+func (r *Registry) Use(middlewares ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middlewares...)
+}
+
+// Get retrieves the provider for a model, decorated by the global
+// middlewares. Returns an error if the model is not registered.
 func (r *Registry) Get(model string) (Provider, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -37,7 +48,7 @@ func (r *Registry) Get(model string) (Provider, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown model: %s", model)
 	}
-	return p, nil
+	return Chain(r.middleware...)(p), nil
 }
 
 // Models returns all registered model names.
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Server-sent events, used by every streaming HTTP API except Bedrock's
+// and Ollama's.
+// Spec: https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+//
+// An event is a block of "field: value" lines ended by a blank line. Lines
+// end in CRLF, LF or CR; lines starting with a colon are comments. Several
+// data lines in one event are joined with newlines.
+
+// sseMaxEvent bounds the size of one event, so a server that never ends a
+// line can't exhaust memory.
+const sseMaxEvent = 16 << 20
+
+// sseEvent is one decoded event.
+type sseEvent struct {
+	Event string // the event field, or "" for an unnamed message
+	Data  string
+	ID    string // the last event ID seen in the stream
+}
+
+// sseReader decodes consecutive events from an event stream.
+type sseReader struct {
+	r      *bufio.Reader
+	lastID string
+	begun  bool // past the optional byte order mark
+	skipLF bool // the last line ended in CR
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// Next reads the next event carrying data. It returns io.EOF at the end of
+// the stream; an event cut off before its blank line is discarded.
+func (s *sseReader) Next() (sseEvent, error) {
+	var (
+		event   string
+		data    strings.Builder
+		hasData bool
+	)
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return sseEvent{}, err
+		}
+		if !s.begun {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			s.begun = true
+		}
+
+		if line == "" {
+			if hasData {
+				return sseEvent{Event: event, Data: data.String(), ID: s.lastID}, nil
+			}
+			event = ""
+			continue
+		}
+		if line[0] == ':' {
+			continue // comment, e.g. a keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+			if data.Len() > sseMaxEvent {
+				return sseEvent{}, fmt.Errorf("event stream: event larger than %d bytes", sseMaxEvent)
+			}
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		}
+		// "retry" only matters to clients that reconnect
+	}
+}
+
+// readLine reads one line without its ending, which may be CRLF, LF or
+// CR. At the end of the stream, an unterminated line is dropped.
+func (s *sseReader) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if s.skipLF {
+			// The LF of a CRLF ending
+			s.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+		switch b {
+		case '\n':
+			return string(line), nil
+		case '\r':
+			s.skipLF = true
+			return string(line), nil
+		}
+		line = append(line, b)
+		if len(line) > sseMaxEvent {
+			return "", fmt.Errorf("event stream: line longer than %d bytes", sseMaxEvent)
+		}
+	}
+}
+
+// errorEvent returns the failure carried by an event, or nil. Vendors that
+// fail after sending the 200 status either name the event "error" or send
+// an error object in the data, in the same shapes as error bodies.
+func errorEvent(ev sseEvent) *APIError {
+	var probe struct {
+		Type  string          `json:"type"`
+		Error json.RawMessage `json:"error"`
+	}
+	json.Unmarshal([]byte(ev.Data), &probe)
+	hasError := len(probe.Error) > 0 && string(probe.Error) != "null"
+	if ev.Event != "error" && probe.Type != "error" && !hasError {
+		return nil
+	}
+
+	e := &APIError{}
+	parseErrorBody(e, []byte(ev.Data))
+	e.Class = classify(e)
+	return e
+}
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSSEReader(t *testing.T) {
+	long := strings.Repeat("x", 100_000)
+
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "data lines",
+			stream: "data: a\n\ndata: b\n\n",
+			want:   []sseEvent{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:   "event names and multi-line data",
+			stream: "event: delta\ndata: one\ndata: two\n\nevent: stop\ndata:{}\n\n",
+			want:   []sseEvent{{Event: "delta", Data: "one\ntwo"}, {Event: "stop", Data: "{}"}},
+		},
+		{
+			name:   "CRLF and CR line endings",
+			stream: "data: a\r\n\r\ndata: b\r\rdata: c\n\n",
+			want:   []sseEvent{{Data: "a"}, {Data: "b"}, {Data: "c"}},
+		},
+		{
+			name:   "comments, retry and empty events skipped",
+			stream: "\uFEFF: OPENROUTER PROCESSING\n\nretry: 1000\n\nevent: ping\n\ndata: a\n\n",
+			want:   []sseEvent{{Data: "a"}},
+		},
+		{
+			name:   "event IDs persist",
+			stream: "id: 1\ndata: a\n\ndata: b\n\n",
+			want:   []sseEvent{{Data: "a", ID: "1"}, {Data: "b", ID: "1"}},
+		},
+		{
+			name:   "lines over 64KB",
+			stream: "data: " + long + "\n\n",
+			want:   []sseEvent{{Data: long}},
+		},
+		{
+			name:   "unterminated event discarded",
+			stream: "data: a\n\ndata: b\n",
+			want:   []sseEvent{{Data: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newSSEReader(strings.NewReader(tt.stream))
+			var got []sseEvent
+			for {
+				ev, err := r.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, ev)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestQueryStream_Incomplete checks that every SSE provider turns error
+// events into typed errors and rejects streams cut off before their
+// terminal event, while accepting a complete stream.
+func TestQueryStream_Incomplete(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	t.Setenv("OPENAI_API_KEY", "test")
+	t.Setenv("GOOGLE_API_KEY", "test")
+	t.Setenv("MISTRAL_API_KEY", "test")
+	t.Setenv("COHERE_API_KEY", "test")
+	t.Setenv("OPENROUTER_API_KEY", "test")
+
+	providers := []struct {
+		name     string
+		new      func(url string) (Provider, error)
+		partial  string // events before the end
+		terminal string
+		failure  string // an in-stream error event
+		want     ErrorClass
+	}{
+		{
+			name: "anthropic",
+			new:  func(url string) (Provider, error) { return NewAnthropic(WithAnthropicBaseURL(url)) },
+			partial: "event: content_block_delta\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}` + "\n\n",
+			terminal: "event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n",
+			failure:  "event: error\n" + `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n",
+			want:     ErrorOverloaded,
+		},
+		{
+			name:     "openai",
+			new:      func(url string) (Provider, error) { return NewOpenAI(WithOpenAIBaseURL(url)) },
+			partial:  "event: response.output_text.delta\n" + `data: {"type":"response.output_text.delta","delta":"Hel"}` + "\n\n",
+			terminal: "event: response.completed\n" + `data: {"type":"response.completed","response":{"status":"completed"}}` + "\n\n",
+			failure:  "event: error\n" + `data: {"type":"error","code":"server_error","message":"boom"}` + "\n\n",
+			want:     ErrorServer,
+		},
+		{
+			name:     "google",
+			new:      func(url string) (Provider, error) { return NewGoogle(WithGoogleBaseURL(url)) },
+			partial:  `data: {"candidates":[{"content":{"parts":[{"text":"Hel"}]}}]}` + "\n\n",
+			terminal: `data: {"candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"STOP"}]}` + "\n\n",
+			failure:  `data: {"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}` + "\n\n",
+			want:     ErrorOverloaded,
+		},
+		{
+			name:     "mistral",
+			new:      func(url string) (Provider, error) { return NewMistral(WithMistralBaseURL(url)) },
+			partial:  `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n",
+			terminal: "data: [DONE]\n\n",
+			failure:  `data: {"error":{"type":"rate_limit_exceeded","message":"slow down"}}` + "\n\n",
+			want:     ErrorRateLimit,
+		},
+		{
+			name:     "openrouter",
+			new:      func(url string) (Provider, error) { return NewOpenRouter(WithOpenRouterBaseURL(url)) },
+			partial:  ": OPENROUTER PROCESSING\n\n" + `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n",
+			terminal: `data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n",
+			failure:  `data: {"error":{"code":"server_error","message":"upstream died"},"choices":[{"delta":{"content":""},"finish_reason":"error"}]}` + "\n\n",
+			want:     ErrorServer,
+		},
+		{
+			name:     "chatcompletions",
+			new:      func(url string) (Provider, error) { return NewChatCompletions(Endpoint{Name: "vllm", BaseURL: url}) },
+			partial:  `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n",
+			terminal: "data: [DONE]\n\n",
+			failure:  `data: {"error":{"code":"server_error","message":"engine died"}}` + "\n\n",
+			want:     ErrorServer,
+		},
+		{
+			name:     "cohere",
+			new:      func(url string) (Provider, error) { return NewCohere(WithCohereBaseURL(url)) },
+			partial:  `data: {"type":"content-delta","delta":{"message":{"content":{"text":"Hel"}}}}` + "\n\n",
+			terminal: `data: {"type":"message-end","delta":{"finish_reason":"COMPLETE"}}` + "\n\n",
+			failure:  "event: error\n" + `data: {"message":"internal server error"}` + "\n\n",
+			want:     ErrorServer,
+		},
+	}
+
+	for _, p := range providers {
+		for _, tc := range []struct {
+			name      string
+			stream    string
+			wantClass ErrorClass // "" for success
+		}{
+			{"complete", p.partial + p.terminal, ""},
+			{"cut off", p.partial, ErrorNetwork},
+			{"error event", p.partial + p.failure, p.want},
+		} {
+			t.Run(p.name+"/"+tc.name, func(t *testing.T) {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "text/event-stream")
+					fmt.Fprint(w, tc.stream)
+				}))
+				defer srv.Close()
+
+				prov, err := p.new(srv.URL)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				resp, err := prov.QueryStream(context.Background(), Request{Model: "m", Prompt: "hi"}, nil)
+
+				if tc.wantClass == "" {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if !strings.HasPrefix(resp.Content, "Hel") {
+						t.Errorf("got content %q", resp.Content)
+					}
+					return
+				}
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Class != tc.wantClass {
+					t.Errorf("got error %v, want class %s", err, tc.wantClass)
+				}
+				// What arrived before the failure is kept
+				if resp.Provider == "" || resp.Model != "m" {
+					t.Errorf("got response %+v, want the partial result", resp)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutLimit names one limit of a timeout policy.
+type TimeoutLimit string
+
+const (
+	TimeoutConnect    TimeoutLimit = "connect"     // dialing the vendor, and the TLS handshake
+	TimeoutFirstToken TimeoutLimit = "first-token" // from sending a request to its first streamed chunk
+	TimeoutIdle       TimeoutLimit = "idle"        // between streamed chunks
+	TimeoutPerModel   TimeoutLimit = "per-model"   // one model's answer, across retries, tool calls and repairs
+	TimeoutRun        TimeoutLimit = "run"         // the whole run, panel and judge
+)
+
+// Timeouts is the timeout policy for queries. A zero limit is disabled.
+//
+// Connect is enforced by the HTTP client, FirstToken and Idle by the
+// middleware, and PerModel and Run by the caller's context, through
+// WithTimeout.
+type Timeouts struct {
+	Connect    time.Duration
+	FirstToken time.Duration
+	Idle       time.Duration
+	PerModel   time.Duration
+	Run        time.Duration
+}
+
+// DefaultTimeouts gives up on connecting after 10s, on a stream that falls
+// silent for 30s, and on a model after 2m. There is no first-token limit,
+// since reasoning models can think for minutes before they answer, and no
+// limit on the run beyond its models'.
+var DefaultTimeouts = Timeouts{
+	Connect:  10 * time.Second,
+	Idle:     30 * time.Second,
+	PerModel: 2 * time.Minute,
+}
+
+// defaultHTTPClient is used by providers not given a client of their own.
+var defaultHTTPClient = DefaultTimeouts.HTTPClient()
+
+// TimeoutError reports which limit of the timeout policy ended a query.
+// It matches context.DeadlineExceeded with errors.Is.
+type TimeoutError struct {
+	Limit TimeoutLimit
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timeout after %s", e.Limit, e.After)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// HTTPClient returns a client for provider requests that gives up on
+// connecting after t.Connect. It sets no overall timeout, which would cut
+// off long streams: requests are bounded by their context instead.
+func (t Timeouts) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t.Connect > 0 {
+		dial := (&net.Dialer{Timeout: t.Connect, KeepAlive: 30 * time.Second}).DialContext
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+				return nil, &TimeoutError{Limit: TimeoutConnect, After: t.Connect}
+			}
+			return conn, err
+		}
+		transport.TLSHandshakeTimeout = t.Connect
+	}
+	return &http.Client{Transport: transport}
+}
+
+// Middleware returns a middleware that ends each query with a
+// *TimeoutError when its first chunk takes longer than t.FirstToken, or
+// when more than t.Idle passes between chunks. Each API call is timed on
+// its own, so a tool call or a retry starts afresh. It returns nil if
+// neither limit is set.
+func (t Timeouts) Middleware() Middleware {
+	if t.FirstToken <= 0 && t.Idle <= 0 {
+		return nil
+	}
+	return func(next Provider) Provider {
+		return streamFunc(func(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+			ctx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+
+			var (
+				mu    sync.Mutex
+				timer *time.Timer
+			)
+			watch := func(limit TimeoutLimit, d time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				if timer != nil {
+					timer.Stop()
+					timer = nil
+				}
+				if d > 0 {
+					timer = time.AfterFunc(d, func() {
+						cancel(&TimeoutError{Limit: limit, After: d})
+					})
+				}
+			}
+			watch(TimeoutFirstToken, t.FirstToken)
+			defer watch("", 0)
+
+			cb := func(chunk string) {
+				if chunk != "" {
+					watch(TimeoutIdle, t.Idle)
+				}
+				if callback != nil {
+					callback(chunk)
+				}
+			}
+			resp, err := next.QueryStream(ctx, req, cb)
+			return resp, TimeoutCause(ctx, err)
+		})
+	}
+}
+
+// WithTimeout is context.WithTimeout with a *TimeoutError naming limit as
+// the cause once d passes. A zero d sets no deadline.
+func WithTimeout(ctx context.Context, limit TimeoutLimit, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, &TimeoutError{Limit: limit, After: d})
+}
+
+// TimeoutCause returns the *TimeoutError that ended ctx in place of err,
+// the error a query failed with because of it, so callers report which
+// limit fired rather than a bare cancellation. Otherwise it returns err.
+func TimeoutCause(ctx context.Context, err error) error {
+	var timeout *TimeoutError
+	if err != nil && ctx.Err() != nil && errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// paced streams a chunk after each delay, then finishes.
+func paced(delays ...time.Duration) Provider {
+	return streamFunc(func(ctx context.Context, req Request, callback StreamCallback) (Response, error) {
+		for _, d := range delays {
+			select {
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			case <-time.After(d):
+			}
+			callback("x")
+		}
+		return Response{Content: "done"}, nil
+	})
+}
+
+func TestTimeouts_Middleware(t *testing.T) {
+	const ms = time.Millisecond
+	policy := Timeouts{FirstToken: 400 * ms, Idle: 200 * ms}
+
+	tests := []struct {
+		name   string
+		delays []time.Duration
+		want   TimeoutLimit // "" for success
+	}{
+		{"within limits", []time.Duration{100 * ms, 20 * ms, 20 * ms, 20 * ms}, ""},
+		{"slow first token", []time.Duration{800 * ms}, TimeoutFirstToken},
+		{"stalls after first token", []time.Duration{10 * ms, 800 * ms}, TimeoutIdle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Chain(policy.Middleware())(paced(tt.delays...))
+			_, err := p.QueryStream(context.Background(), Request{Model: "m"}, nil)
+
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var timeout *TimeoutError
+			if !errors.As(err, &timeout) || timeout.Limit != tt.want {
+				t.Fatalf("got error %v, want a %s timeout", err, tt.want)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Error("timeout doesn't match context.DeadlineExceeded")
+			}
+		})
+	}
+
+	if (Timeouts{PerModel: time.Second}).Middleware() != nil {
+		t.Error("middleware returned with no stream limits set")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), TimeoutRun, 10*time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err := TimeoutCause(ctx, ctx.Err())
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Limit != TimeoutRun {
+		t.Errorf("got %v, want a run timeout", err)
+	}
+	if got := err.Error(); got != "run timeout after 10ms" {
+		t.Errorf("got message %q", got)
+	}
+}
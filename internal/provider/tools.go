@@ -0,0 +1,74 @@
+package provider
+
+import "encoding/json"
+
+// Tool declares a function the model may call.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"` // JSON Schema for the arguments
+}
+
+// ToolCall is a model's request to call a tool.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+
+	// Signature is an opaque token some APIs (Gemini thought signatures)
+	// require to be sent back with the call.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ThinkingBlock is a block of signed reasoning. APIs that sign it
+// (Anthropic) require it to be sent back unchanged ahead of the tool calls
+// that followed it while thinking is on.
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Redacted  string `json:"redacted,omitempty"` // the encrypted data of a redacted block
+}
+
+// ToolResult answers a ToolCall.
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// ToolCaller is implemented by providers that can send Request.Tools and
+// return the model's calls in Response.ToolCalls.
+type ToolCaller interface {
+	// SupportsTools reports whether the model accepts tool declarations.
+	SupportsTools(model string) bool
+}
+
+// CallsTools reports whether p can offer tools to model.
+func CallsTools(p Provider, model string) bool {
+	tc, ok := p.(ToolCaller)
+	return ok && tc.SupportsTools(model)
+}
+
+// toolArguments returns a call's arguments, defaulting to an empty object
+// for APIs that reject a missing one.
+func (c ToolCall) toolArguments() json.RawMessage {
+	if len(c.Arguments) == 0 {
+		return json.RawMessage("{}")
+	}
+	return c.Arguments
+}
+
+// rawArguments converts streamed or string-encoded arguments to JSON. An
+// empty string becomes an empty object; invalid JSON is kept as a string
+// so the handler can report it.
+func rawArguments(s string) json.RawMessage {
+	if s == "" {
+		return json.RawMessage("{}")
+	}
+	if !json.Valid([]byte(s)) {
+		quoted, _ := json.Marshal(s)
+		return quoted
+	}
+	return json.RawMessage(s)
+}
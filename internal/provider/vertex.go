@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vertex AI
+// Docs: https://cloud.google.com/vertex-ai/generative-ai/docs/model-reference/inference
+//
+// Gemini models on Vertex AI are addressed as "vertex:<model>", e.g.
+// vertex:gemini-2.5-pro. Requests go to the regional endpoint for the
+// configured project and location and are authorized with OAuth access
+// tokens minted from a service account key.
+
+// VertexPrefix marks a model name as served by Vertex AI.
+const VertexPrefix = "vertex:"
+
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// WithGoogleVertexProject sets the Google Cloud project for Vertex AI.
+func WithGoogleVertexProject(project string) GoogleOption {
+	return func(g *Google) { g.project = project }
+}
+
+// WithGoogleVertexLocation sets the Vertex AI region, e.g. us-central1 or global.
+func WithGoogleVertexLocation(location string) GoogleOption {
+	return func(g *Google) { g.location = location }
+}
+
+// WithGoogleCredentialsFile sets the service account JSON key file.
+func WithGoogleCredentialsFile(path string) GoogleOption {
+	return func(g *Google) { g.credFile = path }
+}
+
+// NewGoogleVertex creates a Google provider in Vertex AI mode.
+// Reads the service account key from GOOGLE_APPLICATION_CREDENTIALS, the project
+// from GOOGLE_CLOUD_PROJECT (defaulting to the key's project) and the region
+// from GOOGLE_CLOUD_LOCATION (defaulting to us-central1).
+func NewGoogleVertex(opts ...GoogleOption) (*Google, error) {
+	g := &Google{
+		httpClient: defaultHTTPClient,
+		project:    os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		location:   os.Getenv("GOOGLE_CLOUD_LOCATION"),
+		credFile:   os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.credFile == "" {
+		return nil, errors.New("GOOGLE_APPLICATION_CREDENTIALS environment variable required")
+	}
+
+	tokens, err := newServiceAccountTokenSource(g.credFile, g.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	g.tokens = tokens
+
+	if g.project == "" {
+		g.project = tokens.key.ProjectID
+	}
+	if g.project == "" {
+		return nil, errors.New("GOOGLE_CLOUD_PROJECT environment variable required")
+	}
+	if g.location == "" {
+		g.location = "us-central1"
+	}
+	if g.baseURL == "" {
+		if g.location == "global" {
+			g.baseURL = "https://aiplatform.googleapis.com/v1"
+		} else {
+			g.baseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1", g.location)
+		}
+	}
+
+	return g, nil
+}
+
+// serviceAccountKey is the subset of a service account JSON key we need.
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// serviceAccountTokenSource exchanges signed JWT assertions for OAuth access
+// tokens (RFC 7523) and caches them until shortly before they expire.
+type serviceAccountTokenSource struct {
+	key        serviceAccountKey
+	signer     *rsa.PrivateKey
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newServiceAccountTokenSource(path string, httpClient *http.Client) (*serviceAccountTokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading service account key: %w", err)
+	}
+
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("parsing service account key: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("%s is not a service account key", path)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	signer, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing service account private key: %w", err)
+	}
+
+	return &serviceAccountTokenSource{
+		key:        key,
+		signer:     signer,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+// Token returns a valid access token, refreshing it when it is within five
+// minutes of expiry.
+func (s *serviceAccountTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(5*time.Minute).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	assertion, err := s.signJWT()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("creating token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// Failures are classified like API errors, so a rejected key is an
+	// auth error and an unreachable token endpoint can be retried
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return "", requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", networkError(ctx, "reading token response", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError(resp, respBody)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return "", fmt.Errorf("parsing token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+
+	s.token = tokenResp.AccessToken
+	s.expiry = s.now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// signJWT builds an RS256-signed assertion for the token endpoint.
+func (s *serviceAccountTokenSource) signJWT() (string, error) {
+	now := s.now()
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if s.key.PrivateKeyID != "" {
+		header["kid"] = s.key.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   s.key.ClientEmail,
+		"scope": vertexScope,
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(headerJSON) + "." + enc.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey decodes a PEM private key in PKCS#8 (what Google issues)
+// or PKCS#1 form.
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoogleVertex_QueryStream(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokenRequests int
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		r.ParseForm()
+		if err := verifyJWT(r.Form.Get("assertion"), &key.PublicKey); err != nil {
+			t.Errorf("invalid assertion: %v", err)
+		}
+		fmt.Fprint(w, `{"access_token":"ya29.test","expires_in":3600,"token_type":"Bearer"}`)
+	}))
+	defer tokenSrv.Close()
+
+	var gotPath, gotAuth string
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hi\"}]},\"finishReason\":\"STOP\"}]}\n\n")
+	}))
+	defer apiSrv.Close()
+
+	credFile := writeServiceAccountKey(t, key, tokenSrv.URL)
+	g, err := NewGoogleVertex(
+		WithGoogleCredentialsFile(credFile),
+		WithGoogleVertexLocation("europe-west4"),
+		WithGoogleBaseURL(apiSrv.URL),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := g.QueryStream(context.Background(), Request{Model: "vertex:gemini-2.5-pro", Prompt: "hi"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != "hi" || resp.Provider != "vertex" {
+			t.Errorf("got %+v", resp)
+		}
+	}
+
+	if tokenRequests != 1 {
+		t.Errorf("got %d token requests, want 1 (token should be cached)", tokenRequests)
+	}
+	if gotAuth != "Bearer ya29.test" {
+		t.Errorf("got Authorization %q", gotAuth)
+	}
+	wantPath := "/projects/test-project/locations/europe-west4/publishers/google/models/gemini-2.5-pro:streamGenerateContent"
+	if gotPath != wantPath {
+		t.Errorf("got path %q, want %q", gotPath, wantPath)
+	}
+}
+
+func TestGoogleVertex_TokenErrors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantClass ErrorClass
+	}{
+		{"revoked key", http.StatusBadRequest, `{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`, ErrorAuth},
+		{"unauthorized", http.StatusUnauthorized, `{"error":"unauthorized_client"}`, ErrorAuth},
+		{"token endpoint down", http.StatusServiceUnavailable, "upstream connect error", ErrorOverloaded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer tokenSrv.Close()
+
+			g, err := NewGoogleVertex(WithGoogleCredentialsFile(writeServiceAccountKey(t, key, tokenSrv.URL)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = g.Query(context.Background(), Request{Model: "vertex:gemini-2.5-pro", Prompt: "hi"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Class != tt.wantClass {
+				t.Errorf("got %v, want class %s", err, tt.wantClass)
+			}
+		})
+	}
+}
+
+func TestBuildGeminiRequest_Schema(t *testing.T) {
+	schema := json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","$defs":{"name":{"type":"string"}},"type":"object","properties":{"name":{"$ref":"#/$defs/name"}}}`)
+	body, err := json.Marshal(buildGeminiRequest(Request{Model: "gemini-2.5-flash", Prompt: "hi", Schema: schema}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got struct {
+		GenerationConfig map[string]json.RawMessage `json:"generationConfig"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := got.GenerationConfig["responseSchema"]; ok {
+		t.Error("schema sent as responseSchema, which rejects $ref and $defs")
+	}
+	if string(got.GenerationConfig["responseJsonSchema"]) != string(schema) {
+		t.Errorf("got responseJsonSchema %s, want %s", got.GenerationConfig["responseJsonSchema"], schema)
+	}
+}
+
+func writeServiceAccountKey(t *testing.T, key *rsa.PrivateKey, tokenURI string) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa := serviceAccountKey{
+		Type:         "service_account",
+		ProjectID:    "test-project",
+		PrivateKeyID: "key-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "panel@test-project.iam.gserviceaccount.com",
+		TokenURI:     tokenURI,
+	}
+	data, _ := json.Marshal(sa)
+
+	path := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func verifyJWT(token string, pub *rsa.PublicKey) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("got %d parts", len(parts))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+}
@@ -0,0 +1,259 @@
+// Package repotools provides read-only tools for exploring a local
+// repository: listing directories, reading files and searching them.
+//
+// All access goes through an os.Root, so paths (including symlinks) cannot
+// reach outside the repository root.
+package repotools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+// Limits keep tool results small enough to send back to a model.
+const (
+	maxEntries     = 500
+	maxReadLines   = 2000
+	maxReadBytes   = 256 << 10
+	maxGrepResults = 200
+	maxGrepFile    = 1 << 20
+)
+
+// Tool is a tool declaration with the handler that executes it.
+type Tool struct {
+	provider.Tool
+	Handler func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Repo gives read-only access to the files under a root directory.
+type Repo struct {
+	root *os.Root
+}
+
+// Open opens dir as a repository root.
+func Open(dir string) (*Repo, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	return &Repo{root: root}, nil
+}
+
+// Close releases the root directory.
+func (r *Repo) Close() error {
+	return r.root.Close()
+}
+
+// Tools returns the list_directory, read_file and grep tools.
+func (r *Repo) Tools() []Tool {
+	return []Tool{
+		{
+			Tool: provider.Tool{
+				Name:        "list_directory",
+				Description: "List the entries of a directory in the repository. Directories end with a slash.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{` +
+					`"path":{"type":"string","description":"Directory relative to the repository root; defaults to the root"}}}`),
+			},
+			Handler: r.listDirectory,
+		},
+		{
+			Tool: provider.Tool{
+				Name:        "read_file",
+				Description: "Read a text file in the repository, optionally limited to a range of lines. Lines are prefixed with their numbers.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{` +
+					`"path":{"type":"string","description":"File relative to the repository root"},` +
+					`"start_line":{"type":"integer","description":"First line to read, starting at 1"},` +
+					`"end_line":{"type":"integer","description":"Last line to read, inclusive"}},` +
+					`"required":["path"]}`),
+			},
+			Handler: r.readFile,
+		},
+		{
+			Tool: provider.Tool{
+				Name:        "grep",
+				Description: "Search text files in the repository for lines matching a regular expression (RE2 syntax). Results are path:line: text.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{` +
+					`"pattern":{"type":"string","description":"Regular expression to search for"},` +
+					`"path":{"type":"string","description":"Directory or file to search, relative to the repository root; defaults to the root"}},` +
+					`"required":["pattern"]}`),
+			},
+			Handler: r.grep,
+		},
+	}
+}
+
+// clean converts a model-supplied path to a slash-separated path relative
+// to the root. os.Root rejects anything that escapes it.
+func clean(p string) string {
+	p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+	if p == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+func (r *Repo) listDirectory(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	entries, err := fs.ReadDir(r.root.FS(), clean(in.Path))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, e := range entries {
+		if i == maxEntries {
+			fmt.Fprintf(&b, "... %d more entries\n", len(entries)-maxEntries)
+			break
+		}
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		b.WriteString(name + "\n")
+	}
+	if b.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
+
+func (r *Repo) readFile(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if in.StartLine < 1 {
+		in.StartLine = 1
+	}
+	if in.EndLine != 0 && in.EndLine < in.StartLine {
+		return "", errors.New("end_line is before start_line")
+	}
+
+	f, err := r.root.Open(clean(in.Path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var (
+		b       strings.Builder
+		lineNum = 0
+		read    = 0
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), maxReadBytes)
+	for scanner.Scan() {
+		lineNum++
+		if lineNum < in.StartLine {
+			continue
+		}
+		if in.EndLine != 0 && lineNum > in.EndLine {
+			break
+		}
+		line := scanner.Bytes()
+		if bytes.IndexByte(line, 0) >= 0 {
+			return "", errors.New("binary file")
+		}
+		if read == maxReadLines || b.Len()+len(line) > maxReadBytes {
+			fmt.Fprintf(&b, "... truncated at line %d; request a later start_line to continue\n", lineNum-1)
+			break
+		}
+		fmt.Fprintf(&b, "%d: %s\n", lineNum, line)
+		read++
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if read == 0 {
+		return fmt.Sprintf("(no lines in range; the file has %d lines)", lineNum), nil
+	}
+	return b.String(), nil
+}
+
+func (r *Repo) grep(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	re, err := regexp.Compile(in.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var (
+		b       strings.Builder
+		matches = 0
+		errFull = errors.New("result limit reached")
+	)
+	fsys := r.root.FS()
+	err = fs.WalkDir(fsys, clean(in.Path), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxGrepFile {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			return nil // unreadable or binary
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches == maxGrepResults {
+				return errFull
+			}
+			fmt.Fprintf(&b, "%s:%d: %s\n", name, i+1, strings.TrimRight(line, "\r"))
+			matches++
+		}
+		return nil
+	})
+	if errors.Is(err, errFull) {
+		fmt.Fprintf(&b, "... stopped after %d matches; narrow the pattern or path\n", maxGrepResults)
+	} else if err != nil {
+		return "", err
+	}
+
+	if matches == 0 {
+		return "(no matches)", nil
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,114 @@
+package repotools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRepo(t *testing.T) *Repo {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":         "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"pkg/util.go":     "package pkg\n\n// Hello greets.\nfunc Hello() string { return \"hello\" }\n",
+		"pkg/data.bin":    "hello\x00world",
+		".git/HEAD":       "hello\n",
+		"docs/README.txt": "nothing here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("hello secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape.txt")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func call(t *testing.T, repo *Repo, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range repo.Tools() {
+		if tool.Name == name {
+			return tool.Handler(context.Background(), json.RawMessage(args))
+		}
+	}
+	t.Fatalf("no tool %q", name)
+	return "", nil
+}
+
+func TestRepo_Tools(t *testing.T) {
+	repo := testRepo(t)
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{name: "list root", tool: "list_directory", args: `{}`, want: []string{"main.go", "pkg/", ".git/"}},
+		{name: "list subdirectory", tool: "list_directory", args: `{"path": "pkg"}`, want: []string{"util.go"}, notWant: []string{"main.go"}},
+		{name: "list parent stays in root", tool: "list_directory", args: `{"path": "../.."}`, want: []string{"main.go"}},
+		{name: "read whole file", tool: "read_file", args: `{"path": "main.go"}`, want: []string{"1: package main", "5: }"}},
+		{name: "read line range", tool: "read_file", args: `{"path": "main.go", "start_line": 3, "end_line": 4}`, want: []string{"3: func main() {", "4: \tprintln"}, notWant: []string{"1: package"}},
+		{name: "read past end", tool: "read_file", args: `{"path": "main.go", "start_line": 50}`, want: []string{"the file has 5 lines"}},
+		{name: "read binary", tool: "read_file", args: `{"path": "pkg/data.bin"}`, wantErr: true},
+		{name: "read missing", tool: "read_file", args: `{"path": "nope.go"}`, wantErr: true},
+		{name: "read through escaping symlink", tool: "read_file", args: `{"path": "escape.txt"}`, wantErr: true},
+		{name: "read outside with dot-dot", tool: "read_file", args: `{"path": "../secret.txt"}`, wantErr: true},
+		{
+			name: "grep", tool: "grep", args: `{"pattern": "hello"}`,
+			want:    []string{`main.go:4: 	println("hello")`, `pkg/util.go:4: func Hello()`},
+			notWant: []string{"data.bin", ".git", "secret"},
+		},
+		{name: "grep path", tool: "grep", args: `{"pattern": "^package", "path": "pkg"}`, want: []string{"pkg/util.go:1"}, notWant: []string{"main.go"}},
+		{name: "grep no matches", tool: "grep", args: `{"pattern": "zzz"}`, want: []string{"(no matches)"}},
+		{name: "grep bad pattern", tool: "grep", args: `{"pattern": "("}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := call(t, repo, tt.tool, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output missing %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("output contains %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+// RetryPolicy controls how queries that fail with a retryable API error
+// (rate limits, overloads, server and network errors) are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, including the first; 1 disables retries
+	BaseDelay   time.Duration // backoff before the first retry, doubling after each
+	MaxDelay    time.Duration // cap on the backoff, though not on Retry-After
+}
+
+// DefaultRetryPolicy makes up to three attempts, waiting about 1s and 2s
+// between them.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// WithRetry sets the retry policy. New runners use DefaultRetryPolicy.
+func (r *Runner) WithRetry(policy RetryPolicy) *Runner {
+	r.retry = policy
+	return r
+}
+
+// backoff returns the delay before the given retry (1 for the first),
+// with equal jitter: half the exponential delay plus a random part of the
+// other half, so models rate limited together don't retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retrying is a Provider that retries each query according to the
+// runner's policy. A query that has already streamed content is never
+// retried, since the callback can't take that content back.
+type retrying struct {
+	provider.Provider
+	runner *Runner
+	entry  string // panel entry reported to callbacks
+}
+
+func (p retrying) Query(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return p.QueryStream(ctx, req, nil)
+}
+
+func (p retrying) QueryStream(ctx context.Context, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	policy := p.runner.retry
+	for attempt := 1; ; attempt++ {
+		streamed := false
+		cb := callback
+		if callback != nil {
+			cb = func(chunk string) {
+				if chunk != "" {
+					streamed = true
+				}
+				callback(chunk)
+			}
+		}
+
+		resp, err := p.Provider.QueryStream(ctx, req, cb)
+		if err == nil || streamed || attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+		var apiErr *provider.APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() {
+			return resp, err
+		}
+
+		delay := max(policy.backoff(attempt), apiErr.RetryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err // the wait would outlast the timeout
+		}
+		if cb := p.runner.callbacks; cb != nil && cb.OnModelRetry != nil {
+			cb.OnModelRetry(p.entry, attempt+1, policy.MaxAttempts, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
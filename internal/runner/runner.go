@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/johnayoung/llm-consensus/internal/provider"
+	"github.com/johnayoung/llm-consensus/internal/schema"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -17,27 +19,106 @@ type Callbacks struct {
 	OnModelStream   func(model string, chunk string)
 	OnModelComplete func(model string)
 	OnModelError    func(model string, err error)
+	// OnModelRetry is called before waiting delay to make attempt (2 for
+	// the first retry) of maxAttempts after err.
+	OnModelRetry func(model string, attempt, maxAttempts int, delay time.Duration, err error)
+	// OnModelFallback is called when model, a fallback chain, moves on to
+	// next after err.
+	OnModelFallback func(model, next string, err error)
 }
 
 // Result contains the outcomes of querying multiple models.
 type Result struct {
-	Responses    []provider.Response
-	Warnings     []string
-	FailedModels []string
+	Responses     []provider.Response
+	Warnings      []string
+	Failures      []Failure
+	FailedModels  []string
+	SkippedModels []string // models that can't accept an attachment or whose circuit is open
+	Chains        []Chain  // fallback chains, with the model that answered
+
+	// Incomplete holds answers cut off by a timeout or cancellation that
+	// IncompleteExclude left out of Responses
+	Incomplete []provider.Response
+}
+
+// Chain records which model of a fallback chain answered.
+type Chain struct {
+	Models   []string `json:"models"`
+	Answered string   `json:"answered,omitempty"` // empty if none did
+}
+
+// Failure records why a model produced no answer. The API fields are set
+// when the provider returned a *provider.APIError.
+type Failure struct {
+	Model      string              `json:"model"`
+	Error      string              `json:"error"`
+	Class      provider.ErrorClass `json:"class,omitempty"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Type       string              `json:"type,omitempty"`
+	Code       string              `json:"code,omitempty"`
+	RequestID  string              `json:"request_id,omitempty"`
+
+	// Timeout names the limit of the timeout policy that fired, if one did
+	Timeout provider.TimeoutLimit `json:"timeout,omitempty"`
+
+	Err error `json:"-"`
+}
+
+func newFailure(model string, err error) Failure {
+	f := Failure{Model: model, Error: err.Error(), Err: err}
+	var apiErr *provider.APIError
+	if errors.As(err, &apiErr) {
+		f.Class = apiErr.Class
+		f.StatusCode = apiErr.StatusCode
+		f.Type = apiErr.Type
+		f.Code = apiErr.Code
+		f.RequestID = apiErr.RequestID
+	}
+	var timeout *provider.TimeoutError
+	if errors.As(err, &timeout) {
+		f.Timeout = timeout.Limit
+	}
+	return f
 }
 
+// IncompletePolicy decides what becomes of an answer that was cut off by
+// a timeout or cancellation after it began streaming.
+type IncompletePolicy string
+
+const (
+	IncompleteInclude IncompletePolicy = "include" // answer with the partial text, marked incomplete
+	IncompleteExclude IncompletePolicy = "exclude" // keep the partial text apart from Responses
+	IncompleteFail    IncompletePolicy = "fail"    // count the model as failed
+)
+
 // Runner orchestrates parallel LLM queries.
 type Runner struct {
-	registry  *provider.Registry
-	timeout   time.Duration
-	callbacks *Callbacks
+	registry   *provider.Registry
+	timeout    time.Duration
+	callbacks  *Callbacks
+	params     provider.Params
+	perModel   map[string]provider.Params
+	history    []provider.Message
+	attach     []provider.Attachment
+	schema     *schema.Schema
+	tools      []provider.Tool
+	handlers   map[string]ToolHandler
+	maxSteps   int
+	retry      RetryPolicy
+	incomplete IncompletePolicy
+
+	breaker      *provider.Breaker
+	providerName func(model string) string
 }
 
-// New creates a runner with the given registry and per-model timeout.
+// New creates a runner with the given registry and per-model timeout;
+// zero sets none.
 func New(registry *provider.Registry, timeout time.Duration) *Runner {
 	return &Runner{
-		registry: registry,
-		timeout:  timeout,
+		registry:   registry,
+		timeout:    timeout,
+		retry:      DefaultRetryPolicy,
+		incomplete: IncompleteInclude,
 	}
 }
 
@@ -47,68 +128,171 @@ func (r *Runner) WithCallbacks(cb *Callbacks) *Runner {
 	return r
 }
 
+// WithParams sets generation parameters sent with every query. Entries in
+// perModel are merged over defaults for the named model.
+func (r *Runner) WithParams(defaults provider.Params, perModel map[string]provider.Params) *Runner {
+	r.params = defaults
+	r.perModel = perModel
+	return r
+}
+
+// WithHistory sets prior conversation turns sent ahead of the prompt.
+func (r *Runner) WithHistory(history []provider.Message) *Runner {
+	r.history = history
+	return r
+}
+
+// WithAttachments sets files sent with the prompt. Models whose provider
+// can't accept one of them are skipped with a warning.
+func (r *Runner) WithAttachments(attachments []provider.Attachment) *Runner {
+	r.attach = attachments
+	return r
+}
+
+// WithIncomplete sets what happens to answers cut off by a timeout or
+// cancellation. New runners use IncompleteInclude. With a schema, cut-off
+// answers always count as failures, since they can't conform to it.
+func (r *Runner) WithIncomplete(policy IncompletePolicy) *Runner {
+	r.incomplete = policy
+	return r
+}
+
+// WithBreaker guards each model with b, using its own circuit and that of
+// the provider providerName returns for it. The breaker sees each query
+// once, after its retries, so a run of retries counts as one failure.
+func (r *Runner) WithBreaker(b *provider.Breaker, providerName func(model string) string) *Runner {
+	r.breaker = b
+	r.providerName = providerName
+	return r
+}
+
+// WithSchema requires every answer to be JSON conforming to s. Answers
+// that still fail validation after one repair attempt count as failures.
+func (r *Runner) WithSchema(s *schema.Schema) *Runner {
+	r.schema = s
+	return r
+}
+
+// unsupported returns the MIME type of the first attachment the model
+// can't accept, or "" if it accepts them all.
+func (r *Runner) unsupported(p provider.Provider, model string) string {
+	for _, a := range r.attach {
+		if !provider.Accepts(p, model, a.MIMEType) {
+			return a.MIMEType
+		}
+	}
+	return ""
+}
+
+// paramsFor returns the generation parameters for a model.
+func (r *Runner) paramsFor(model string) provider.Params {
+	if override, ok := r.perModel[model]; ok {
+		return r.params.Merge(override)
+	}
+	return r.params
+}
+
 // Run queries all models concurrently and collects results.
 // Uses best-effort strategy: partial failures don't abort the run.
+//
+// Each entry in models is a model name or a fallback chain such as
+// "claude-opus-4-5|claude-sonnet-4-5": when a model fails with anything but
+// a user error the next one is tried, with its own timeout. Callbacks
+// receive the entry, so a chain reports as one model.
 func (r *Runner) Run(ctx context.Context, models []string, prompt string) (*Result, error) {
 	var (
-		mu           sync.Mutex
-		responses    []provider.Response
-		warnings     []string
-		failedModels []string
+		mu            sync.Mutex
+		responses     []provider.Response
+		warnings      []string
+		failures      []Failure
+		skippedModels []string
+		chains        []Chain
+		incomplete    []provider.Response
 	)
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	for _, model := range models {
+	for _, entry := range models {
 		g.Go(func() error {
-			// Per-model timeout
-			modelCtx, cancel := context.WithTimeout(ctx, r.timeout)
-			defer cancel()
-
 			// Notify start
 			if r.callbacks != nil && r.callbacks.OnModelStart != nil {
-				r.callbacks.OnModelStart(model)
+				r.callbacks.OnModelStart(entry)
 			}
 
-			p, err := r.registry.Get(model)
-			if err != nil {
+			warn := func(w string) {
 				mu.Lock()
-				warnings = append(warnings, fmt.Sprintf("%s: %v", model, err))
-				failedModels = append(failedModels, model)
+				warnings = append(warnings, w)
 				mu.Unlock()
-				if r.callbacks != nil && r.callbacks.OnModelError != nil {
-					r.callbacks.OnModelError(model, err)
-				}
-				return nil // best effort: don't fail entire run
 			}
 
-			// Use streaming query with callback
-			streamCallback := func(chunk string) {
-				if r.callbacks != nil && r.callbacks.OnModelStream != nil {
-					r.callbacks.OnModelStream(model, chunk)
+			chain := SplitChain(entry)
+			var (
+				model string
+				resp  provider.Response
+				err   error
+			)
+			for i, next := range chain {
+				if i > 0 {
+					warn(fmt.Sprintf("%s: %v; falling back to %s", model, err, next))
+					if r.callbacks != nil && r.callbacks.OnModelFallback != nil {
+						r.callbacks.OnModelFallback(entry, next, err)
+					}
+				}
+				model = next
+				resp, err = r.query(ctx, entry, model, prompt, warn)
+				if err == nil || ctx.Err() != nil || provider.IsUserError(err) {
+					break
 				}
 			}
 
-			resp, err := p.QueryStream(modelCtx, provider.Request{
-				Model:  model,
-				Prompt: prompt,
-			}, streamCallback)
-
 			mu.Lock()
 			defer mu.Unlock()
 
+			if len(chain) > 1 {
+				c := Chain{Models: chain}
+				if err == nil {
+					c.Answered = model
+				}
+				chains = append(chains, c)
+			}
+
+			var skip skipError
+			if errors.As(err, &skip) {
+				warnings = append(warnings, fmt.Sprintf("%s: %v", model, err))
+				skippedModels = append(skippedModels, model)
+				if r.callbacks != nil && r.callbacks.OnModelError != nil {
+					r.callbacks.OnModelError(entry, err)
+				}
+				return nil
+			}
 			if err != nil {
+				failures = append(failures, newFailure(model, err))
+				if r.callbacks != nil && r.callbacks.OnModelError != nil {
+					r.callbacks.OnModelError(entry, err)
+				}
+				return nil // best effort: don't fail entire run
+			}
+
+			if resp.Incomplete {
+				err := fmt.Errorf("incomplete: %s", resp.IncompleteReason)
 				warnings = append(warnings, fmt.Sprintf("%s: %v", model, err))
-				failedModels = append(failedModels, model)
+				if r.incomplete == IncompleteExclude {
+					incomplete = append(incomplete, resp)
+				} else {
+					responses = append(responses, resp)
+				}
 				if r.callbacks != nil && r.callbacks.OnModelError != nil {
-					r.callbacks.OnModelError(model, err)
+					r.callbacks.OnModelError(entry, err)
 				}
-				return nil // best effort
+				return nil
 			}
 
 			responses = append(responses, resp)
+			if resp.Truncated() {
+				warnings = append(warnings, fmt.Sprintf("%s: response truncated (finish reason %s)", model, resp.FinishReason))
+			}
 			if r.callbacks != nil && r.callbacks.OnModelComplete != nil {
-				r.callbacks.OnModelComplete(model)
+				r.callbacks.OnModelComplete(entry)
 			}
 			return nil
 		})
@@ -119,13 +303,129 @@ func (r *Runner) Run(ctx context.Context, models []string, prompt string) (*Resu
 		return nil, err
 	}
 
+	failedModels := make([]string, len(failures))
+	for i, f := range failures {
+		failedModels[i] = f.Model
+	}
+
 	if len(responses) == 0 {
-		return nil, errors.New("all models failed: " + fmt.Sprintf("%v", warnings))
+		errs := make([]string, 0, len(failures)+len(warnings))
+		for _, f := range failures {
+			errs = append(errs, fmt.Sprintf("%s: %s", f.Model, f.Error))
+		}
+		return nil, errors.New("all models failed: " + strings.Join(append(errs, warnings...), "; "))
 	}
 
 	return &Result{
-		Responses:    responses,
-		Warnings:     warnings,
-		FailedModels: failedModels,
+		Responses:     responses,
+		Warnings:      warnings,
+		Failures:      failures,
+		FailedModels:  failedModels,
+		SkippedModels: skippedModels,
+		Chains:        chains,
+		Incomplete:    incomplete,
 	}, nil
 }
+
+// query asks one model, with its own timeout. Progress is reported under
+// the panel entry the model belongs to.
+func (r *Runner) query(ctx context.Context, entry, model, prompt string, warn func(string)) (provider.Response, error) {
+	ctx, cancel := provider.WithTimeout(ctx, provider.TimeoutPerModel, r.timeout)
+	defer cancel()
+
+	p, err := r.registry.Get(model)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	if mime := r.unsupported(p, model); mime != "" {
+		return provider.Response{}, skipError{fmt.Errorf("does not accept %s attachments", mime)}
+	}
+
+	// Use streaming query with callback, keeping what the current step has
+	// sent in case the answer is cut off
+	var partial strings.Builder
+	streamCallback := func(chunk string) {
+		partial.WriteString(chunk)
+		if r.callbacks != nil && r.callbacks.OnModelStream != nil {
+			r.callbacks.OnModelStream(entry, chunk)
+		}
+	}
+
+	req := provider.Request{
+		Model:       model,
+		Prompt:      prompt,
+		Messages:    r.history,
+		Attachments: r.attach,
+		Params:      r.paramsFor(model),
+	}
+
+	// Each API call is retried on its own; models that can call tools get
+	// them through the tool loop
+	var q provider.Provider = p
+	if r.retry.MaxAttempts > 1 {
+		q = retrying{Provider: p, runner: r, entry: entry}
+	}
+	if r.breaker != nil {
+		q = provider.Chain(r.breaker.Middleware(r.providerName(model), model))(q)
+	}
+	if len(r.tools) > 0 {
+		if provider.CallsTools(p, model) {
+			q = toolLoop{Provider: q, runner: r, newStep: partial.Reset}
+		} else {
+			warn(fmt.Sprintf("%s: tools not supported, answering without them", model))
+		}
+	}
+
+	start := time.Now()
+	var resp provider.Response
+	if r.schema != nil {
+		resp, err = r.schema.Query(ctx, q, req, streamCallback)
+	} else {
+		resp, err = q.QueryStream(ctx, req, streamCallback)
+	}
+
+	// A timeout or cancellation after the answer began leaves a partial one
+	err = provider.TimeoutCause(ctx, err)
+	var timeout *provider.TimeoutError
+	timedOut := errors.As(err, &timeout)
+	if err != nil && (ctx.Err() != nil || timedOut) && partial.Len() > 0 && r.incomplete != IncompleteFail && r.schema == nil {
+		reason := "cancelled"
+		if timedOut {
+			reason = timeout.Error()
+		}
+		return provider.Response{
+			Model:            model,
+			Content:          partial.String(),
+			Provider:         resp.Provider,
+			RequestID:        resp.RequestID,
+			Usage:            resp.Usage,
+			Latency:          time.Since(start),
+			Transcript:       resp.Transcript,
+			Incomplete:       true,
+			IncompleteReason: reason,
+		}, nil
+	}
+
+	var open *provider.CircuitOpenError
+	if errors.As(err, &open) {
+		return resp, skipError{open}
+	}
+	return resp, err
+}
+
+// skipError is why a model was skipped rather than failed: it can't accept
+// the attachments, or its circuit is open.
+type skipError struct{ err error }
+
+func (e skipError) Error() string { return "skipped: " + e.err.Error() }
+func (e skipError) Unwrap() error { return e.err }
+
+// SplitChain splits a panel entry into the models of its fallback chain,
+// in the order they are tried.
+func SplitChain(entry string) []string {
+	models := strings.Split(entry, "|")
+	for i := range models {
+		models[i] = strings.TrimSpace(models[i])
+	}
+	return models
+}
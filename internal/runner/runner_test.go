@@ -2,7 +2,13 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -46,7 +52,7 @@ func TestRunner_Run(t *testing.T) {
 				}))
 			},
 			wantRespLen: 1,
-			wantWarnLen: 1,
+			wantWarnLen: 0,
 			wantFailLen: 1,
 		},
 		{
@@ -100,10 +106,140 @@ func TestRunner_Run(t *testing.T) {
 			if len(result.FailedModels) != tt.wantFailLen {
 				t.Errorf("got %d failed models, want %d", len(result.FailedModels), tt.wantFailLen)
 			}
+
+			if len(result.Failures) != tt.wantFailLen {
+				t.Errorf("got %d failures, want %d", len(result.Failures), tt.wantFailLen)
+			}
 		})
 	}
 }
 
+func TestRunner_FailureFromAPIError(t *testing.T) {
+	reg := provider.NewRegistry()
+	reg.Register("limited", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{}, fmt.Errorf("query: %w", &provider.APIError{
+			StatusCode: 429,
+			Type:       "rate_limit_error",
+			Message:    "slow down",
+			RequestID:  "req_1",
+			Class:      provider.ErrorRateLimit,
+		})
+	}))
+	reg.Register("ok", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: "ok", Content: "fine"}, nil
+	}))
+
+	result, err := New(reg, 5*time.Second).
+		WithRetry(RetryPolicy{MaxAttempts: 1}).
+		Run(context.Background(), []string{"limited", "ok"}, "q")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Failures) != 1 {
+		t.Fatalf("got %d failures, want 1", len(result.Failures))
+	}
+	f := result.Failures[0]
+	if f.Model != "limited" || f.Class != provider.ErrorRateLimit || f.StatusCode != 429 || f.Type != "rate_limit_error" || f.RequestID != "req_1" {
+		t.Errorf("got failure %+v", f)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("failures should not be repeated as warnings: %v", result.Warnings)
+	}
+}
+
+// streamThenFail streams a chunk and then fails as if the connection dropped.
+type streamThenFail struct{ calls *int }
+
+func (p streamThenFail) Query(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return p.QueryStream(ctx, req, nil)
+}
+
+func (p streamThenFail) QueryStream(ctx context.Context, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	*p.calls++
+	if callback != nil {
+		callback("partial")
+	}
+	return provider.Response{}, &provider.APIError{Class: provider.ErrorOverloaded, Message: "overloaded"}
+}
+
+func TestRunner_Retry(t *testing.T) {
+	overloaded := &provider.APIError{StatusCode: 529, Class: provider.ErrorOverloaded, Message: "overloaded"}
+	unauthorized := &provider.APIError{StatusCode: 401, Class: provider.ErrorAuth, Message: "bad key"}
+
+	tests := []struct {
+		name        string
+		failures    []error // returned by successive calls before succeeding
+		stream      bool
+		wantCalls   int
+		wantRetries []int
+		wantErr     bool
+	}{
+		{name: "recovers after retries", failures: []error{overloaded, overloaded}, wantCalls: 3, wantRetries: []int{2, 3}},
+		{name: "gives up after max attempts", failures: []error{overloaded, overloaded, overloaded}, wantCalls: 3, wantRetries: []int{2, 3}, wantErr: true},
+		{name: "not retryable", failures: []error{unauthorized}, wantCalls: 1, wantErr: true},
+		{name: "plain errors are not retried", failures: []error{errors.New("boom")}, wantCalls: 1, wantErr: true},
+		{name: "never after streaming", stream: true, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			reg := provider.NewRegistry()
+			if tt.stream {
+				reg.Register("m", streamThenFail{calls: &calls})
+			} else {
+				reg.Register("m", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+					calls++
+					if calls <= len(tt.failures) {
+						return provider.Response{}, tt.failures[calls-1]
+					}
+					return provider.Response{Model: req.Model, Content: "ok"}, nil
+				}))
+			}
+
+			var retries []int
+			r := New(reg, 5*time.Second).
+				WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}).
+				WithCallbacks(&Callbacks{
+					OnModelRetry: func(model string, attempt, maxAttempts int, delay time.Duration, err error) {
+						if maxAttempts != 3 || delay > 5*time.Millisecond {
+							t.Errorf("got max attempts %d, delay %v", maxAttempts, delay)
+						}
+						retries = append(retries, attempt)
+					},
+				})
+			_, err := r.Run(context.Background(), []string{"m"}, "q")
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !slices.Equal(retries, tt.wantRetries) {
+				t.Errorf("got retries %v, want %v", retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRunner_RetryAfterBeyondDeadline(t *testing.T) {
+	calls := 0
+	reg := provider.NewRegistry()
+	reg.Register("m", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		calls++
+		return provider.Response{}, &provider.APIError{StatusCode: 429, Class: provider.ErrorRateLimit, RetryAfter: time.Minute}
+	}))
+
+	start := time.Now()
+	if _, err := New(reg, time.Second).Run(context.Background(), []string{"m"}, "q"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("waited for a Retry-After past the timeout: %d calls in %v", calls, time.Since(start))
+	}
+}
+
 func TestRunner_Timeout(t *testing.T) {
 	reg := provider.NewRegistry()
 	reg.Register("slow-model", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
@@ -127,3 +263,447 @@ func TestRunner_Timeout(t *testing.T) {
 		t.Error("expected failed models to include slow-model")
 	}
 }
+
+// streamThenHang streams a chunk and then waits until the query is cut off.
+type streamThenHang struct{}
+
+func (p streamThenHang) Query(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return p.QueryStream(ctx, req, nil)
+}
+
+func (streamThenHang) QueryStream(ctx context.Context, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	if callback != nil {
+		callback("The answer is")
+	}
+	<-ctx.Done()
+	return provider.Response{}, fmt.Errorf("reading stream: %w", ctx.Err())
+}
+
+func TestRunner_Incomplete(t *testing.T) {
+	tests := []struct {
+		policy         IncompletePolicy
+		wantResponses  int
+		wantIncomplete int
+		wantFailures   int
+	}{
+		{IncompleteInclude, 2, 0, 0},
+		{IncompleteExclude, 1, 1, 0},
+		{IncompleteFail, 1, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			reg := provider.NewRegistry()
+			reg.Register("slow", streamThenHang{})
+			reg.Register("fast", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+				return provider.Response{Model: req.Model, Content: "42"}, nil
+			}))
+
+			result, err := New(reg, 50*time.Millisecond).
+				WithIncomplete(tt.policy).
+				Run(context.Background(), []string{"slow", "fast"}, "q")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Responses) != tt.wantResponses || len(result.Incomplete) != tt.wantIncomplete || len(result.Failures) != tt.wantFailures {
+				t.Fatalf("got %d responses, %d incomplete, %d failures", len(result.Responses), len(result.Incomplete), len(result.Failures))
+			}
+
+			for _, resp := range append(result.Responses, result.Incomplete...) {
+				if resp.Model != "slow" {
+					continue
+				}
+				if !resp.Incomplete || resp.Content != "The answer is" || !strings.Contains(resp.IncompleteReason, "per-model timeout") {
+					t.Errorf("got %+v, want the partial answer marked incomplete", resp)
+				}
+			}
+		})
+	}
+}
+
+// toolThenHang calls a tool, then streams part of its answer and waits
+// until the query is cut off.
+type toolThenHang struct{}
+
+func (p toolThenHang) Query(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return p.QueryStream(ctx, req, nil)
+}
+
+func (toolThenHang) QueryStream(ctx context.Context, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	if len(req.ToolTurns) == 0 {
+		callback("Let me add that up.")
+		return provider.Response{Model: req.Model, Provider: "fake", Usage: provider.Usage{OutputTokens: 10},
+			ToolCalls: []provider.ToolCall{{ID: "call-1", Name: "noop"}}}, nil
+	}
+	callback("The sum is")
+	<-ctx.Done()
+	return provider.Response{Model: req.Model, Provider: "fake", RequestID: "req-2", Usage: provider.Usage{InputTokens: 7}},
+		fmt.Errorf("reading stream: %w", ctx.Err())
+}
+
+func (toolThenHang) SupportsTools(model string) bool { return true }
+
+func TestRunner_IncompleteToolStep(t *testing.T) {
+	reg := provider.NewRegistry()
+	reg.Register("tools", toolThenHang{})
+
+	result, err := New(reg, 50*time.Millisecond).
+		WithTool(provider.Tool{Name: "noop"}, func(ctx context.Context, args json.RawMessage) (string, error) { return "", nil }).
+		Run(context.Background(), []string{"tools"}, "q")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp := result.Responses[0]
+	if !resp.Incomplete || resp.Content != "The sum is" {
+		t.Errorf("got content %q, want only the interrupted step's text", resp.Content)
+	}
+	if resp.Provider != "fake" || resp.RequestID != "req-2" || resp.Usage.OutputTokens != 10 || resp.Usage.InputTokens != 7 {
+		t.Errorf("got %+v, want the provider, request ID and usage so far", resp)
+	}
+	if len(resp.Transcript) != 2 {
+		t.Errorf("got %d transcript turns, want the tool exchange", len(resp.Transcript))
+	}
+}
+
+func TestRunner_Timeouts(t *testing.T) {
+	hang := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		<-ctx.Done()
+		return provider.Response{}, fmt.Errorf("sending request: %w", ctx.Err())
+	})
+	stream := provider.Timeouts{FirstToken: 50 * time.Millisecond, Idle: 50 * time.Millisecond}.Middleware()
+
+	reg := provider.NewRegistry()
+	reg.Register("silent", hang, stream)
+	reg.Register("stalls", streamThenHang{}, stream)
+	reg.Register("slow", hang)
+	reg.Register("fast", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: req.Model, Content: "42"}, nil
+	}))
+
+	result, err := New(reg, 200*time.Millisecond).Run(context.Background(), []string{"silent", "stalls", "slow", "fast"}, "q")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]provider.TimeoutLimit)
+	for _, f := range result.Failures {
+		got[f.Model] = f.Timeout
+	}
+	if got["silent"] != provider.TimeoutFirstToken || got["slow"] != provider.TimeoutPerModel {
+		t.Errorf("got failures %+v, want first-token and per-model timeouts", result.Failures)
+	}
+	for _, resp := range result.Responses {
+		if resp.Model == "stalls" && resp.IncompleteReason != "idle timeout after 50ms" {
+			t.Errorf("got incomplete reason %q, want an idle timeout", resp.IncompleteReason)
+		}
+	}
+}
+
+func TestRunner_Params(t *testing.T) {
+	reg := provider.NewRegistry()
+	got := make(chan provider.Request, 2)
+	echo := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		got <- req
+		return provider.Response{Model: req.Model, Content: "ok"}, nil
+	})
+	reg.Register("model-a", echo)
+	reg.Register("model-b", echo)
+
+	temp, override := 0.7, 0.2
+	runner := New(reg, 5*time.Second).WithParams(
+		provider.Params{System: "be brief", Temperature: &temp, MaxTokens: 1000},
+		map[string]provider.Params{"model-b": {Temperature: &override}},
+	)
+	if _, err := runner.Run(context.Background(), []string{"model-a", "model-b"}, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(got)
+
+	for req := range got {
+		want := temp
+		if req.Model == "model-b" {
+			want = override
+		}
+		if req.System != "be brief" || req.MaxTokens != 1000 {
+			t.Errorf("%s: defaults not applied: %+v", req.Model, req.Params)
+		}
+		if req.Temperature == nil || *req.Temperature != want {
+			t.Errorf("%s: got temperature %v, want %v", req.Model, req.Temperature, want)
+		}
+	}
+}
+
+// visionProvider accepts image attachments.
+type visionProvider struct{ provider.ProviderFunc }
+
+func (visionProvider) SupportsAttachment(model, mimeType string) bool {
+	return mimeType == "image/png"
+}
+
+func TestRunner_Attachments(t *testing.T) {
+	reg := provider.NewRegistry()
+	echo := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		if len(req.Attachments) != 2 {
+			t.Errorf("%s: got %d attachments, want 2", req.Model, len(req.Attachments))
+		}
+		return provider.Response{Model: req.Model, Content: "ok"}, nil
+	})
+	reg.Register("vision", visionProvider{echo})
+	reg.Register("text-only", echo)
+
+	runner := New(reg, 5*time.Second).WithAttachments([]provider.Attachment{
+		{Name: "notes.txt", MIMEType: "text/plain", Data: []byte("notes")},
+		{Name: "chart.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+	})
+	result, err := runner.Run(context.Background(), []string{"vision", "text-only"}, "describe")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Responses) != 1 || result.Responses[0].Model != "vision" {
+		t.Errorf("expected only vision to respond, got %+v", result.Responses)
+	}
+	if len(result.SkippedModels) != 1 || result.SkippedModels[0] != "text-only" {
+		t.Errorf("expected text-only skipped, got %v", result.SkippedModels)
+	}
+	if len(result.FailedModels) != 0 {
+		t.Errorf("skipped model counted as failed: %v", result.FailedModels)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "image/png") {
+		t.Errorf("expected warning naming image/png, got %v", result.Warnings)
+	}
+}
+
+// toolProvider supports tool calling.
+type toolProvider struct{ provider.ProviderFunc }
+
+func (toolProvider) SupportsTools(model string) bool { return true }
+
+func TestRunner_Tools(t *testing.T) {
+	reg := provider.NewRegistry()
+	reg.Register("caller", toolProvider{func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		if len(req.Tools) != 1 {
+			t.Errorf("got %d tools, want 1", len(req.Tools))
+		}
+		if len(req.ToolTurns) == 0 {
+			return provider.Response{Model: req.Model, ToolCalls: []provider.ToolCall{
+				{ID: "call-1", Name: "add", Arguments: json.RawMessage(`{"a": 2, "b": 3}`)},
+			}, Usage: provider.Usage{OutputTokens: 10}}, nil
+		}
+		results := req.ToolTurns[1].ToolResults
+		if len(results) != 1 || results[0].CallID != "call-1" || results[0].Content != "5" {
+			t.Errorf("unexpected tool results: %+v", results)
+		}
+		return provider.Response{Model: req.Model, Content: "The sum is 5", Usage: provider.Usage{OutputTokens: 5}}, nil
+	}})
+	reg.Register("plain", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: req.Model, Content: "ok"}, nil
+	}))
+
+	add := func(ctx context.Context, args json.RawMessage) (string, error) {
+		var in struct{ A, B int }
+		if err := json.Unmarshal(args, &in); err != nil {
+			return "", err
+		}
+		return strconv.Itoa(in.A + in.B), nil
+	}
+	runner := New(reg, 5*time.Second).WithTool(provider.Tool{Name: "add", Parameters: json.RawMessage(`{"type": "object"}`)}, add)
+
+	result, err := runner.Run(context.Background(), []string{"caller", "plain"}, "2+3?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, resp := range result.Responses {
+		if resp.Model != "caller" {
+			continue
+		}
+		if resp.Content != "The sum is 5" || len(resp.Transcript) != 2 {
+			t.Errorf("got content %q with %d transcript turns", resp.Content, len(resp.Transcript))
+		}
+		if resp.Usage.OutputTokens != 15 {
+			t.Errorf("usage not summed over steps: %+v", resp.Usage)
+		}
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "plain: tools not supported") {
+		t.Errorf("expected warning for plain, got %v", result.Warnings)
+	}
+}
+
+func TestRunner_ToolsMaxSteps(t *testing.T) {
+	reg := provider.NewRegistry()
+	calls := 0
+	reg.Register("looper", toolProvider{func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		calls++
+		if req.NoToolCalls {
+			if len(req.Tools) == 0 {
+				t.Error("last step dropped the tool declarations")
+			}
+			return provider.Response{Model: req.Model, Content: "answer"}, nil
+		}
+		return provider.Response{Model: req.Model, ToolCalls: []provider.ToolCall{{ID: "x", Name: "missing"}}}, nil
+	}})
+	// stubborn ignores NoToolCalls
+	reg.Register("stubborn", toolProvider{func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: req.Model, ToolCalls: []provider.ToolCall{{ID: "x", Name: "missing"}}}, nil
+	}})
+
+	runner := New(reg, 5*time.Second).
+		WithTool(provider.Tool{Name: "noop"}, func(ctx context.Context, args json.RawMessage) (string, error) { return "", nil }).
+		WithMaxSteps(2)
+
+	result, err := runner.Run(context.Background(), []string{"looper"}, "loop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+	if resp := result.Responses[0]; resp.Content != "answer" || len(resp.Transcript) != 4 {
+		t.Errorf("got %q with %d transcript turns, want the answer after 2 tool steps", resp.Content, len(resp.Transcript))
+	}
+
+	_, err = runner.Run(context.Background(), []string{"stubborn"}, "loop")
+	if err == nil || !strings.Contains(err.Error(), "no answer after 2 tool steps") {
+		t.Errorf("got error %v, want tool step limit", err)
+	}
+}
+
+func TestRunner_CircuitOpen(t *testing.T) {
+	until := time.Date(2026, 1, 1, 14, 5, 0, 0, time.Local)
+	reg := provider.NewRegistry()
+	reg.Register("benched", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{}, &provider.CircuitOpenError{Key: req.Model, Until: until}
+	}))
+	reg.Register("ok", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: req.Model, Content: "ok"}, nil
+	}))
+
+	result, err := New(reg, 5*time.Second).Run(context.Background(), []string{"benched", "ok"}, "hi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.SkippedModels) != 1 || result.SkippedModels[0] != "benched" {
+		t.Errorf("expected benched skipped, got %v", result.SkippedModels)
+	}
+	if len(result.Failures) != 0 {
+		t.Errorf("skipped model counted as failed: %+v", result.Failures)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0] != "benched: skipped: circuit open until 14:05" {
+		t.Errorf("got warnings %v", result.Warnings)
+	}
+}
+
+func TestRunner_BreakerAfterRetries(t *testing.T) {
+	b, err := provider.NewBreaker("", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := make(map[string]int)
+	var mu sync.Mutex
+	failing := func(class provider.ErrorClass) provider.Provider {
+		return provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+			mu.Lock()
+			calls[req.Model]++
+			mu.Unlock()
+			return provider.Response{}, &provider.APIError{StatusCode: 529, Class: class}
+		})
+	}
+	reg := provider.NewRegistry()
+	reg.Register("overloaded", failing(provider.ErrorOverloaded))
+	reg.Register("limited", failing(provider.ErrorRateLimit))
+	reg.Register("ok", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		return provider.Response{Model: req.Model, Content: "ok"}, nil
+	}))
+
+	r := New(reg, 5*time.Second).
+		WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}).
+		WithBreaker(b, func(model string) string { return model })
+	run := func() {
+		if _, err := r.Run(context.Background(), []string{"overloaded", "limited", "ok"}, "q"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Three failed attempts are one failed query
+	run()
+	if calls["overloaded"] != 3 || b.State("overloaded") != provider.CircuitClosed {
+		t.Fatalf("after %d attempts got state %s, want closed", calls["overloaded"], b.State("overloaded"))
+	}
+	run()
+	if b.State("overloaded") != provider.CircuitOpen {
+		t.Errorf("got state %s after two failed queries, want open", b.State("overloaded"))
+	}
+	if b.State("limited") != provider.CircuitClosed {
+		t.Errorf("rate limits opened the circuit")
+	}
+}
+
+func TestRunner_Fallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error  // returned by the first model
+		wantAnswered string // "" if the chain should give up
+		wantWarnings int
+	}{
+		{"outage falls back", &provider.APIError{StatusCode: 529, Class: provider.ErrorOverloaded, Message: "overloaded"}, "backup", 1},
+		{"unknown model falls back", &provider.APIError{StatusCode: 404, Class: provider.ErrorInvalidRequest, Message: "no such model"}, "backup", 1},
+		{"circuit open falls back", &provider.CircuitOpenError{Key: "primary", Until: time.Now().Add(time.Minute)}, "backup", 1},
+		{"user error stops", &provider.APIError{StatusCode: 400, Class: provider.ErrorInvalidRequest, Message: "bad request"}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu        sync.Mutex
+				fallbacks []string
+			)
+			answer := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+				return provider.Response{Model: req.Model, Content: "ok"}, nil
+			})
+			reg := provider.NewRegistry()
+			reg.Register("primary", provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+				return provider.Response{}, tt.err
+			}))
+			reg.Register("backup", answer)
+			reg.Register("other", answer)
+
+			result, err := New(reg, 5*time.Second).
+				WithRetry(RetryPolicy{MaxAttempts: 1}).
+				WithCallbacks(&Callbacks{
+					OnModelFallback: func(model, next string, err error) {
+						mu.Lock()
+						fallbacks = append(fallbacks, model+" -> "+next)
+						mu.Unlock()
+					},
+				}).
+				Run(context.Background(), []string{"primary | backup", "other"}, "q")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Chains) != 1 || !slices.Equal(result.Chains[0].Models, []string{"primary", "backup"}) || result.Chains[0].Answered != tt.wantAnswered {
+				t.Errorf("got chains %+v, want answered by %q", result.Chains, tt.wantAnswered)
+			}
+			if len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("got warnings %v", result.Warnings)
+			}
+			if tt.wantAnswered == "" {
+				if len(result.Failures) != 1 || result.Failures[0].Model != "primary" || len(fallbacks) != 0 {
+					t.Errorf("got failures %+v, fallbacks %v; want primary failed without falling back", result.Failures, fallbacks)
+				}
+				return
+			}
+			if len(result.Responses) != 2 || len(result.Failures) != 0 || len(result.SkippedModels) != 0 {
+				t.Errorf("got %d responses, failures %+v, skipped %v", len(result.Responses), result.Failures, result.SkippedModels)
+			}
+			if !strings.Contains(result.Warnings[0], "falling back to backup") {
+				t.Errorf("got warning %q", result.Warnings[0])
+			}
+			if !slices.Equal(fallbacks, []string{"primary | backup -> backup"}) {
+				t.Errorf("got fallback callbacks %v", fallbacks)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+// DefaultMaxSteps bounds how many rounds of tool calls a model may make
+// before it must answer.
+const DefaultMaxSteps = 8
+
+// ToolHandler executes a tool call. args is the JSON the model sent; the
+// returned string is passed back to the model as the result. An error is
+// reported to the model as a failed call rather than ending the run.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// WithTool registers a tool offered to every model whose provider supports
+// tool calling. Handlers may run concurrently for different models.
+func (r *Runner) WithTool(tool provider.Tool, handler ToolHandler) *Runner {
+	if r.handlers == nil {
+		r.handlers = make(map[string]ToolHandler)
+	}
+	r.tools = append(r.tools, tool)
+	r.handlers[tool.Name] = handler
+	return r
+}
+
+// WithMaxSteps sets how many rounds of tool calls a model may make before
+// it is asked to answer without them. Zero means DefaultMaxSteps.
+func (r *Runner) WithMaxSteps(n int) *Runner {
+	r.maxSteps = n
+	return r
+}
+
+// toolLoop is a Provider that answers tool calls with the runner's handlers
+// until the model gives a final answer.
+type toolLoop struct {
+	provider.Provider
+	runner *Runner
+
+	// newStep, if set, is called before each step after the first, so the
+	// caller can tell which streamed text belongs to the step under way
+	newStep func()
+}
+
+func (t toolLoop) Query(ctx context.Context, req provider.Request) (provider.Response, error) {
+	return t.QueryStream(ctx, req, nil)
+}
+
+// QueryStream runs the loop. The final response carries usage and latency
+// summed over every step and the tool exchange as its Transcript, as does
+// the response returned with an error from a step.
+func (t toolLoop) QueryStream(ctx context.Context, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	maxSteps := t.runner.maxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	req.Tools = t.runner.tools
+	var (
+		usage   provider.Usage
+		latency time.Duration
+	)
+	for step := 0; ; step++ {
+		if step > 0 && t.newStep != nil {
+			t.newStep()
+		}
+		// The last step must answer: the tools stay declared for the calls
+		// already made, but the model may not call them again
+		req.NoToolCalls = step == maxSteps
+		resp, err := t.Provider.QueryStream(ctx, req, callback)
+		usage = usage.Add(resp.Usage)
+		latency += resp.Latency
+		if err != nil {
+			resp.Usage = usage
+			resp.Latency = latency
+			resp.Transcript = req.ToolTurns
+			return resp, err
+		}
+
+		if len(resp.ToolCalls) == 0 || step == maxSteps {
+			resp.Usage = usage
+			resp.Latency = latency
+			resp.Transcript = req.ToolTurns
+			if len(resp.ToolCalls) > 0 {
+				return resp, fmt.Errorf("no answer after %d tool steps", maxSteps)
+			}
+			return resp, nil
+		}
+
+		results := make([]provider.ToolResult, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			results[i] = t.runner.callTool(ctx, call)
+		}
+		req.ToolTurns = append(req.ToolTurns,
+			provider.Message{Role: provider.RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking},
+			provider.Message{Role: provider.RoleTool, ToolResults: results},
+		)
+	}
+}
+
+// callTool runs the handler for call. Unknown tools and handler errors
+// become error results so the model can recover.
+func (r *Runner) callTool(ctx context.Context, call provider.ToolCall) provider.ToolResult {
+	result := provider.ToolResult{CallID: call.ID, Name: call.Name}
+
+	handler, ok := r.handlers[call.Name]
+	if !ok {
+		result.Content = fmt.Sprintf("unknown tool %q", call.Name)
+		result.IsError = true
+		return result
+	}
+
+	out, err := handler(ctx, call.Arguments)
+	if err != nil {
+		result.Content = err.Error()
+		result.IsError = true
+		return result
+	}
+	result.Content = out
+	return result
+}
@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+// repairPrompt asks a model to correct an answer that failed validation.
+const repairPrompt = `Your previous response did not conform to the required JSON Schema:
+%v
+
+Reply with only the corrected JSON document.`
+
+// repairSeparator is streamed between the first answer and its repair.
+const repairSeparator = "\n\n--- repairing to match the schema ---\n\n"
+
+// Query sends req to p with the schema attached and validates the answer.
+// An answer that fails validation gets one repair attempt, in which the
+// model sees its answer and the validation error. On success the response
+// Content holds the bare JSON document; usage and latency cover both
+// attempts.
+func (s *Schema) Query(ctx context.Context, p provider.Provider, req provider.Request, callback provider.StreamCallback) (provider.Response, error) {
+	req.Schema = s.raw
+
+	resp, err := p.QueryStream(ctx, req, callback)
+	if err != nil {
+		return resp, err
+	}
+
+	doc := Extract(resp.Content)
+	verr := s.Validate([]byte(doc))
+	if verr == nil {
+		resp.Content = doc
+		return resp, nil
+	}
+
+	// The failed exchange becomes history for the repair turn. Text
+	// attachments are already inlined into the conversation, and binary
+	// ones stay on the original prompt turn, so the model can still see
+	// the document it is correcting.
+	repair := req
+	repair.Messages = append(req.Conversation(),
+		provider.Message{Role: provider.RoleAssistant, Content: resp.Content})
+	repair.Prompt = fmt.Sprintf(repairPrompt, verr)
+	repair.Attachments = nil
+
+	// Mark where the repair starts in the streamed text
+	if callback != nil {
+		callback(repairSeparator)
+	}
+	retry, err := p.QueryStream(ctx, repair, callback)
+	if err != nil {
+		return resp, fmt.Errorf("schema repair: %w", err)
+	}
+	retry.Usage = retry.Usage.Add(resp.Usage)
+	retry.Latency += resp.Latency
+
+	doc = Extract(retry.Content)
+	if err := s.Validate([]byte(doc)); err != nil {
+		return retry, fmt.Errorf("response does not match schema after repair: %w", err)
+	}
+	retry.Content = doc
+	return retry, nil
+}
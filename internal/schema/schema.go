@@ -0,0 +1,414 @@
+// Package schema validates model output against a JSON Schema.
+//
+// Validation covers the subset of JSON Schema that structured-output APIs
+// accept: type, properties, required, additionalProperties, items, enum,
+// const, anyOf/oneOf/allOf, length, size and range bounds, pattern, and
+// local $ref pointers into $defs or definitions.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema document.
+type Schema struct {
+	raw  json.RawMessage
+	root map[string]any
+}
+
+// Parse parses a JSON Schema document. The root must be an object.
+func Parse(data []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	return &Schema{raw: json.RawMessage(data), root: root}, nil
+}
+
+// Raw returns the schema document as given to Parse.
+func (s *Schema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// Validate parses data as JSON and checks it against the schema.
+func (s *Schema) Validate(data []byte) error {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid JSON: trailing data after value")
+	}
+	return s.validate(s.root, v, "$")
+}
+
+// Extract returns the JSON document in a model answer, removing a
+// surrounding markdown code fence if there is one.
+func Extract(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:] // drop the language tag line
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
+
+// validate checks v against the schema node at path.
+func (s *Schema) validate(node map[string]any, v any, path string) error {
+	if ref, ok := node["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return s.validate(target, v, path)
+	}
+
+	if t, ok := node["type"]; ok {
+		if err := checkType(t, v, path); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := node["const"]; ok && !equal(c, v) {
+		return fmt.Errorf("%s: must equal %v", path, c)
+	}
+	if enum, ok := node["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return equal(e, v) }) {
+			return fmt.Errorf("%s: must be one of %v", path, enum)
+		}
+	}
+
+	if err := s.combinators(node, v, path); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return s.validateObject(node, v, path)
+	case []any:
+		return s.validateArray(node, v, path)
+	case string:
+		return validateString(node, v, path)
+	case json.Number:
+		return validateNumber(node, v, path)
+	}
+	return nil
+}
+
+// combinators applies allOf, anyOf and oneOf.
+func (s *Schema) combinators(node map[string]any, v any, path string) error {
+	for _, sub := range subschemas(node["allOf"]) {
+		if err := s.validate(sub, v, path); err != nil {
+			return err
+		}
+	}
+
+	if alts := subschemas(node["anyOf"]); len(alts) > 0 {
+		if !slices.ContainsFunc(alts, func(sub map[string]any) bool { return s.validate(sub, v, path) == nil }) {
+			return fmt.Errorf("%s: matches none of anyOf", path)
+		}
+	}
+
+	if one := subschemas(node["oneOf"]); len(one) > 0 {
+		matches := 0
+		for _, sub := range one {
+			if s.validate(sub, v, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: matches %d of oneOf, want exactly 1", path, matches)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(node map[string]any, obj map[string]any, path string) error {
+	for _, name := range stringList(node["required"]) {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	props, _ := node["properties"].(map[string]any)
+	// Sort keys so the first error reported is stable
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		child := path + "." + k
+		if sub, ok := props[k].(map[string]any); ok {
+			if err := s.validate(sub, obj[k], child); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := node["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unexpected property %q", path, k)
+			}
+		case map[string]any:
+			if err := s.validate(extra, obj[k], child); err != nil {
+				return err
+			}
+		}
+	}
+
+	if n, ok := intValue(node["minProperties"]); ok && len(obj) < n {
+		return fmt.Errorf("%s: has %d properties, want at least %d", path, len(obj), n)
+	}
+	if n, ok := intValue(node["maxProperties"]); ok && len(obj) > n {
+		return fmt.Errorf("%s: has %d properties, want at most %d", path, len(obj), n)
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(node map[string]any, arr []any, path string) error {
+	if n, ok := intValue(node["minItems"]); ok && len(arr) < n {
+		return fmt.Errorf("%s: has %d items, want at least %d", path, len(arr), n)
+	}
+	if n, ok := intValue(node["maxItems"]); ok && len(arr) > n {
+		return fmt.Errorf("%s: has %d items, want at most %d", path, len(arr), n)
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		for i, item := range arr {
+			if err := s.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	if unique, _ := node["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if equal(arr[i], arr[j]) {
+					return fmt.Errorf("%s: items %d and %d are equal", path, i, j)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(node map[string]any, str, path string) error {
+	n := utf8.RuneCountInString(str)
+	if min, ok := intValue(node["minLength"]); ok && n < min {
+		return fmt.Errorf("%s: length %d, want at least %d", path, n, min)
+	}
+	if max, ok := intValue(node["maxLength"]); ok && n > max {
+		return fmt.Errorf("%s: length %d, want at most %d", path, n, max)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", path, pattern, err)
+		}
+		if !re.MatchString(str) {
+			return fmt.Errorf("%s: does not match pattern %q", path, pattern)
+		}
+	}
+	return nil
+}
+
+func validateNumber(node map[string]any, num json.Number, path string) error {
+	f, err := num.Float64()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if min, ok := floatValue(node["minimum"]); ok && f < min {
+		return fmt.Errorf("%s: %v is less than minimum %v", path, f, min)
+	}
+	if max, ok := floatValue(node["maximum"]); ok && f > max {
+		return fmt.Errorf("%s: %v is greater than maximum %v", path, f, max)
+	}
+	if min, ok := floatValue(node["exclusiveMinimum"]); ok && f <= min {
+		return fmt.Errorf("%s: %v must be greater than %v", path, f, min)
+	}
+	if max, ok := floatValue(node["exclusiveMaximum"]); ok && f >= max {
+		return fmt.Errorf("%s: %v must be less than %v", path, f, max)
+	}
+	return nil
+}
+
+// checkType checks v against a "type" keyword, which is a type name or a
+// list of them.
+func checkType(t any, v any, path string) error {
+	names := stringList(t)
+	if s, ok := t.(string); ok {
+		names = []string{s}
+	}
+	for _, name := range names {
+		if hasType(name, v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: got %s, want %s", path, typeName(v), strings.Join(names, " or "))
+}
+
+func hasType(name string, v any) bool {
+	switch name {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// resolve follows a local JSON pointer such as "#/$defs/item".
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are resolved", ref)
+	}
+
+	var node any = s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = m[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+
+	target, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q is not a schema", ref)
+	}
+	return target, nil
+}
+
+// equal compares JSON values decoded by Parse (schema side, float64
+// numbers) and Validate (instance side, json.Number).
+func equal(a, b any) bool {
+	fa, aNum := floatValue(a)
+	fb, bNum := floatValue(b)
+	if aNum || bNum {
+		return aNum && bNum && fa == fb
+	}
+
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func subschemas(v any) []map[string]any {
+	list, _ := v.([]any)
+	var out []map[string]any
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// stringList converts a JSON array of strings, skipping other values.
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	var out []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func floatValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func intValue(v any) (int, bool) {
+	f, ok := floatValue(v)
+	return int(f), ok
+}
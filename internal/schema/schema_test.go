@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/llm-consensus/internal/provider"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"answer": {"type": "string", "minLength": 1},
+		"confidence": {"type": "number", "minimum": 0, "maximum": 1},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+	},
+	"required": ["answer", "confidence"],
+	"additionalProperties": false,
+	"$defs": {
+		"tag": {"type": "string", "enum": ["fact", "opinion"]}
+	}
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{name: "valid", doc: `{"answer": "Paris", "confidence": 0.9, "tags": ["fact"]}`},
+		{name: "integer is a number", doc: `{"answer": "Paris", "confidence": 1}`},
+		{name: "not JSON", doc: `Paris`, wantErr: "invalid JSON"},
+		{name: "missing required", doc: `{"answer": "Paris"}`, wantErr: `missing required property "confidence"`},
+		{name: "wrong type", doc: `{"answer": 42, "confidence": 0.5}`, wantErr: "$.answer: got number, want string"},
+		{name: "out of range", doc: `{"answer": "Paris", "confidence": 1.5}`, wantErr: "greater than maximum"},
+		{name: "extra property", doc: `{"answer": "Paris", "confidence": 0.5, "source": "x"}`, wantErr: `unexpected property "source"`},
+		{name: "enum through ref", doc: `{"answer": "Paris", "confidence": 0.5, "tags": ["guess"]}`, wantErr: "$.tags[0]: must be one of"},
+		{name: "empty string", doc: `{"answer": "", "confidence": 0.5}`, wantErr: "want at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Validate([]byte(tt.doc))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	got := Extract("```json\n{\"a\": 1}\n```\n")
+	if got != `{"a": 1}` {
+		t.Errorf("got %q", got)
+	}
+	if got := Extract(` {"a": 1} `); got != `{"a": 1}` {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSchema_QueryRepair(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var calls []provider.Request
+	p := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		calls = append(calls, req)
+		if len(calls) == 1 {
+			return provider.Response{Content: `{"answer": "Paris"}`, Usage: provider.Usage{InputTokens: 10, OutputTokens: 5}}, nil
+		}
+		return provider.Response{Content: "```json\n{\"answer\": \"Paris\", \"confidence\": 0.8}\n```", Usage: provider.Usage{InputTokens: 20, OutputTokens: 8}}, nil
+	})
+
+	image := provider.Attachment{Name: "map.png", MIMEType: "image/png", Data: []byte("png")}
+	var streamed strings.Builder
+	resp, err := s.Query(context.Background(), p, provider.Request{Model: "m", Prompt: "Capital of France?", Attachments: []provider.Attachment{image}}, func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if string(calls[0].Schema) != testSchema {
+		t.Error("schema not passed to provider")
+	}
+	repair := calls[1]
+	if len(repair.Messages) != 2 || repair.Messages[1].Content != `{"answer": "Paris"}` {
+		t.Errorf("repair turn missing failed answer: %+v", repair.Messages)
+	}
+	if atts := repair.Messages[0].Attachments; len(atts) != 1 || atts[0].Name != "map.png" {
+		t.Errorf("repair dropped the image from the original turn: %+v", repair.Messages[0])
+	}
+	if !strings.Contains(streamed.String(), `"Paris"}`+repairSeparator+"```json") {
+		t.Errorf("attempts not separated in the stream: %q", streamed.String())
+	}
+	if !strings.Contains(repair.Prompt, `missing required property "confidence"`) {
+		t.Errorf("repair prompt missing validation error: %q", repair.Prompt)
+	}
+
+	if resp.Content != `{"answer": "Paris", "confidence": 0.8}` {
+		t.Errorf("got content %q", resp.Content)
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 13 {
+		t.Errorf("usage not summed across attempts: %+v", resp.Usage)
+	}
+}
+
+func TestSchema_QueryRepairFails(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	calls := 0
+	p := provider.ProviderFunc(func(ctx context.Context, req provider.Request) (provider.Response, error) {
+		calls++
+		return provider.Response{Content: "not json"}, nil
+	})
+
+	if _, err := s.Query(context.Background(), p, provider.Request{Model: "m", Prompt: "q"}, nil); err == nil {
+		t.Fatal("expected error after failed repair")
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want exactly one repair attempt", calls)
+	}
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"sync"
@@ -33,6 +34,7 @@ const (
 	StatusPending ModelStatus = iota
 	StatusRunning
 	StatusStreaming
+	StatusRetrying
 	StatusComplete
 	StatusFailed
 )
@@ -47,6 +49,18 @@ type ModelState struct {
 	CharCount int
 	TokenEst  int // rough token estimate
 	LastChunk string
+
+	// WaitUntil is when a running model's client-side rate limit wait ends
+	WaitUntil time.Time
+
+	// Fallback is the model of a fallback chain now being queried, once
+	// an earlier one has failed
+	Fallback string
+
+	// Retry state while StatusRetrying
+	Attempt     int
+	MaxAttempts int
+	RetryAt     time.Time
 }
 
 // Progress displays real-time progress of LLM queries.
@@ -144,6 +158,46 @@ func (p *Progress) ModelStreaming(model string, chunk string) {
 	}
 }
 
+// ModelWaiting records that a model is queued for wait by a client-side
+// rate limit before it can connect.
+func (p *Progress) ModelWaiting(model string, wait time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state, ok := p.models[model]; ok {
+		state.WaitUntil = time.Now().Add(wait)
+	}
+}
+
+// ModelRetrying marks a model as waiting delay before retry attempt of
+// maxAttempts.
+func (p *Progress) ModelRetrying(model string, attempt, maxAttempts int, delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state, ok := p.models[model]; ok {
+		state.Status = StatusRetrying
+		state.Attempt = attempt
+		state.MaxAttempts = maxAttempts
+		state.RetryAt = time.Now().Add(delay)
+	}
+}
+
+// ModelFallingBack marks a fallback chain as starting over with its next
+// model.
+func (p *Progress) ModelFallingBack(model, next string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state, ok := p.models[model]; ok {
+		state.Status = StatusRunning
+		state.StartTime = time.Now()
+		state.CharCount = 0
+		state.TokenEst = 0
+		state.Fallback = next
+	}
+}
+
 // ModelCompleted marks a model as finished.
 func (p *Progress) ModelCompleted(model string) {
 	p.mu.Lock()
@@ -207,13 +261,30 @@ func (p *Progress) renderModelLine(state *ModelState) {
 	case StatusRunning:
 		icon = spinner(time.Now())
 		color = Yellow
+		if wait := time.Until(state.WaitUntil); wait > 0 {
+			color = Dim
+			status = fmt.Sprintf("waiting for rate limit %.0fs", math.Ceil(wait.Seconds()))
+			break
+		}
 		elapsed := time.Since(state.StartTime)
 		status = fmt.Sprintf("connecting... %.1fs", elapsed.Seconds())
+		if state.Fallback != "" {
+			status = fmt.Sprintf("falling back to %s... %.1fs", state.Fallback, elapsed.Seconds())
+		}
 	case StatusStreaming:
 		icon = spinner(time.Now())
 		color = Cyan
 		elapsed := time.Since(state.StartTime)
 		status = fmt.Sprintf("streaming ~%d tokens %.1fs", state.TokenEst, elapsed.Seconds())
+	case StatusRetrying:
+		icon = spinner(time.Now())
+		color = Yellow
+		status = fmt.Sprintf("retrying (%d/%d)", state.Attempt, state.MaxAttempts)
+		if wait := time.Until(state.RetryAt); wait > 0 {
+			status += fmt.Sprintf(" in %.0fs", math.Ceil(wait.Seconds()))
+		} else {
+			status += "..."
+		}
 	case StatusComplete:
 		icon = "✓"
 		color = Green
@@ -315,6 +386,19 @@ func PrintSummary(w io.Writer, totalModels, successful, failed int, totalTime ti
 	fmt.Fprintf(w, "Total time: %.1fs\n", totalTime.Seconds())
 }
 
+// PrintTokenUsage prints total token counts for the run.
+func PrintTokenUsage(w io.Writer, input, output, reasoning, cached int) {
+	fmt.Fprintf(w, "Tokens: %d in", input)
+	if cached > 0 {
+		fmt.Fprintf(w, " (%d cached)", cached)
+	}
+	fmt.Fprintf(w, ", %d out", output)
+	if reasoning > 0 {
+		fmt.Fprintf(w, " (%d reasoning)", reasoning)
+	}
+	fmt.Fprintln(w)
+}
+
 // IsTerminal checks if the given file is a terminal.
 func IsTerminal(f *os.File) bool {
 	stat, _ := f.Stat()